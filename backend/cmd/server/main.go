@@ -2,156 +2,270 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"appdirect-workshop-backend/internal/buildinfo"
+	"appdirect-workshop-backend/internal/cache"
+	"appdirect-workshop-backend/internal/config"
+	"appdirect-workshop-backend/internal/events"
 	"appdirect-workshop-backend/internal/handlers"
+	"appdirect-workshop-backend/internal/jobs"
+	"appdirect-workshop-backend/internal/live"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/mail"
+	"appdirect-workshop-backend/internal/metrics"
 	"appdirect-workshop-backend/internal/middleware"
+	"appdirect-workshop-backend/internal/openapi"
+	"appdirect-workshop-backend/internal/outbox"
 	"appdirect-workshop-backend/internal/repository"
+	"appdirect-workshop-backend/internal/storage"
+	"appdirect-workshop-backend/internal/tracing"
+	"appdirect-workshop-backend/internal/webhooks"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 )
 
 func main() {
-	// Load environment variables from .env file in backend directory or root
-	if err := godotenv.Load(); err != nil {
-		// Try loading from backend directory
-		if err2 := godotenv.Load("./backend/.env"); err2 != nil {
-			// Try loading from root
-			if err3 := godotenv.Load("../.env"); err3 != nil {
-				log.Println("No .env file found, using environment variables")
-			}
-		}
+	cfg, printOnly, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-
-	// Get configuration from environment
-	projectID := os.Getenv("FIRESTORE_PROJECT_ID")
-	subDocID := os.Getenv("FIRESTORE_SUBCOLLECTION_ID")
-	serviceAccountPath := os.Getenv("FIREBASE_SERVICE_ACCOUNT_PATH")
-	port := os.Getenv("PORT")
-	corsOrigin := os.Getenv("CORS_ORIGIN")
-
-	if port == "" {
-		port = "8080"
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
 	}
-	if corsOrigin == "" {
-		corsOrigin = "http://localhost:3000"
+	if printOnly {
+		cfg.Print(os.Stdout)
+		return
 	}
 
-	// Validate required environment variables
-	if projectID == "" || subDocID == "" {
-		log.Fatal("FIRESTORE_PROJECT_ID and FIRESTORE_SUBCOLLECTION_ID are required")
+	// Logging is set up first so everything else logs through it
+	logLevel, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
 	}
+	logger, err := logging.New(os.Stdout, logLevel, cfg.LogFormat)
+	if err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	slog.SetDefault(logger)
+	slog.Info("Configuration loaded", "config", cfg)
 
 	// Initialize Firestore repository
 	ctx := context.Background()
-	repo, err := repository.NewRepository(ctx, projectID, subDocID, serviceAccountPath)
+
+	// Tracing is set up first so the repository's spans are exported
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracesExporter)
+	if err != nil {
+		fatal("Failed to set up tracing", "error", err)
+	}
+	repo, err := repository.NewRepository(ctx, cfg.ProjectID, cfg.EventID, cfg.ServiceAccountPath)
 	if err != nil {
-		log.Fatalf("Failed to initialize repository: %v", err)
+		fatal("Failed to initialize repository", "error", err)
 	}
 	defer repo.Close()
 
-	// Initialize handlers
-	attendeeHandler := handlers.NewAttendeeHandler(repo)
-	speakerHandler := handlers.NewSpeakerHandler(repo)
-	sessionHandler := handlers.NewSessionHandler(repo)
-	adminHandler := handlers.NewAdminHandler(repo)
+	// Initialize media storage
+	blobs, err := storage.NewLocalStore(cfg.MediaDir)
+	if err != nil {
+		fatal("Failed to initialize media storage", "error", err)
+	}
+	photoUploader := handlers.NewPhotoUploader(blobs, cfg.PhotoMaxBytes, cfg.MediaBaseURL)
 
-	// Setup Gin router
-	if os.Getenv("GIN_MODE") == "release" {
-		gin.SetMode(gin.ReleaseMode)
+	// Background workers run until shutdown, which waits for them so the
+	// scheduler releases its lease and no outbox event is left half handled
+	background, stopBackground := context.WithCancel(ctx)
+	defer stopBackground()
+	var workers sync.WaitGroup
+	goBackground := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(background)
+		}()
 	}
-	router := gin.Default()
 
-	// CORS configuration
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{corsOrigin}
-	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Password"}
-	config.AllowCredentials = true
-	router.Use(cors.New(config))
-
-	// Health check
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok"})
-	})
+	// Live updates are fanned out to connected clients in process
+	bus := events.NewBus()
+	stats := live.NewStatsBroadcaster(repo, bus)
+	goBackground(stats.Run)
 
-	// Public API routes
-	api := router.Group("/api")
-	{
-		// Sessions
-		api.GET("/sessions", sessionHandler.GetAllSessions)
-		api.GET("/sessions/:id", sessionHandler.GetSession)
+	// Webhook deliveries are stored in Firestore and sent in the background
+	dispatcher := webhooks.NewDispatcher(repo)
+	goBackground(dispatcher.Run)
 
-		// Speakers
-		api.GET("/speakers", speakerHandler.GetAllSpeakers)
-		api.GET("/speakers/:id", speakerHandler.GetSpeaker)
+	// Events written to the outbox with each change are handed to their
+	// side effects at least once
+	outboxDispatcher := outbox.NewDispatcher(repo, bus)
+	outboxDispatcher.Handle(dispatcher.Enqueue)
 
-		// Attendees
-		api.GET("/attendees/count", attendeeHandler.GetAttendeeCount)
-		api.POST("/attendees", attendeeHandler.RegisterAttendee)
+	// Emails are logged instead of sent when no SMTP server is configured
+	var sender mail.Sender = mail.LogSender{}
+	if cfg.SMTPAddr != "" {
+		smtpSender, err := mail.NewSMTPSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+		if err != nil {
+			fatal("Failed to configure email", "error", err)
+		}
+		sender = smtpSender
 	}
 
-	// Admin API routes (password protected)
-	admin := router.Group("/api/admin")
-	admin.Use(middleware.AdminAuth())
-	{
-		// Attendees
-		admin.GET("/attendees", attendeeHandler.GetAllAttendees)
-		admin.GET("/attendees/:id", attendeeHandler.GetAttendee)
-		admin.DELETE("/attendees/:id", attendeeHandler.DeleteAttendee)
+	// Scheduled jobs run on whichever instance holds the scheduler lease
+	scheduler := jobs.NewScheduler(repo)
+	reminders := jobs.NewReminders(repo, scheduler, sender, cfg.ReminderLead)
+	outboxDispatcher.Handle(reminders.HandleEvent)
+	goBackground(outboxDispatcher.Run)
+
+	// Public session and speaker lists are served from memory, and dropped
+	// whenever an admin writes anything
+	agenda := cache.NewAgenda(repo, cfg.AgendaCacheTTL, cfg.AgendaMaxAge)
 
-		// Speakers
-		admin.GET("/speakers", speakerHandler.GetAllSpeakers)
-		admin.POST("/speakers", speakerHandler.CreateSpeaker)
-		admin.PUT("/speakers/:id", speakerHandler.UpdateSpeaker)
-		admin.DELETE("/speakers/:id", speakerHandler.DeleteSpeaker)
+	// Initialize handlers
+	attendeeHandler := handlers.NewAttendeeHandler(repo, bus, stats)
+	speakerHandler := handlers.NewSpeakerHandler(repo, bus, photoUploader, agenda)
+	sessionHandler := handlers.NewSessionHandler(repo, bus, agenda)
+	adminHandler := handlers.NewAdminHandler(repo)
+	mediaHandler := handlers.NewMediaHandler(blobs)
+	feedbackHandler := handlers.NewFeedbackHandler(repo)
+	questionHandler := handlers.NewQuestionHandler(repo, bus)
+	pollHandler := handlers.NewPollHandler(repo, bus)
+	backupHandler := handlers.NewBackupHandler(repo)
+	trashHandler := handlers.NewTrashHandler(repo, bus, photoUploader, cfg.TrashRetention)
+	webhookHandler := handlers.NewWebhookHandler(repo, dispatcher)
+	jobHandler := handlers.NewJobHandler(repo)
+	healthHandler := handlers.NewHealthHandler(map[string]handlers.Check{
+		"firestore": repo.Ping,
+		"storage":   blobs.Ping,
+	}, 2*time.Second)
 
-		// Sessions
-		admin.GET("/sessions", sessionHandler.GetAllSessions)
-		admin.POST("/sessions", sessionHandler.CreateSession)
-		admin.PUT("/sessions/:id", sessionHandler.UpdateSession)
-		admin.DELETE("/sessions/:id", sessionHandler.DeleteSession)
+	spec, err := openapi.JSON()
+	if err != nil {
+		fatal("Failed to build OpenAPI document", "error", err)
+	}
+	docsHandler := handlers.NewDocsHandler(spec)
 
-		// Analytics
-		admin.GET("/analytics/designation", adminHandler.GetDesignationBreakdown)
+	// Recurring jobs, on cron schedules in UTC
+	for _, job := range []struct {
+		name, schedule string
+		run            func(context.Context) error
+	}{
+		{"purge-trash", "0 * * * *", trashHandler.PurgeExpired},
+		{"purge-expired", "30 * * * *", jobs.PurgeExpired(repo)},
+		{"analytics-snapshot", "0 2 * * *", jobs.SnapshotAnalytics(repo)},
+	} {
+		if err := scheduler.Every(job.name, job.schedule, job.run); err != nil {
+			fatal("Failed to schedule jobs", "error", err)
+		}
 	}
+	goBackground(scheduler.Run)
 
-	// Start server
+	// Setup Gin router
+	gin.SetMode(cfg.GinMode)
+	router := gin.New()
+	router.Use(tracing.Middleware(), logging.Middleware(logger), logging.Recovery(), metrics.Middleware(), middleware.Compress())
+	metrics.RegisterStats(stats.Snapshot)
+
+	// CORS configuration
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = cfg.CORSOrigins
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Email", "X-Admin-Password", "If-Match", "If-None-Match", "Idempotency-Key", logging.RequestIDHeader, "traceparent", "tracestate", "baggage"}
+	corsConfig.ExposeHeaders = []string{"ETag", "Idempotent-Replayed", logging.RequestIDHeader}
+	corsConfig.AllowCredentials = true
+	router.Use(cors.New(corsConfig))
+
+	registerRoutes(router, routeHandlers{
+		attendees:  attendeeHandler,
+		speakers:   speakerHandler,
+		sessions:   sessionHandler,
+		admin:      adminHandler,
+		media:      mediaHandler,
+		feedback:   feedbackHandler,
+		questions:  questionHandler,
+		polls:      pollHandler,
+		backup:     backupHandler,
+		trash:      trashHandler,
+		webhooks:   webhookHandler,
+		jobs:       jobHandler,
+		health:     healthHandler,
+		docs:       docsHandler,
+		adminAuth:  middleware.AdminAuth(repo, cfg.AdminPassword),
+		invalidate: middleware.InvalidateOnWrite(agenda.Invalidate),
+		idempotent: middleware.Idempotency(repo, cfg.IdempotencyTTL),
+		metrics:    metrics.Handler(cfg.MetricsToken),
+	})
+
+	// Start server. Server-Sent Events streams only end with their
+	// request, so closing the bus on shutdown ends them for Shutdown to
+	// return.
 	srv := &http.Server{
-		Addr:    ":" + port,
+		Addr:    ":" + cfg.Port,
 		Handler: router,
 	}
+	srv.RegisterOnShutdown(bus.Close)
 
 	// Graceful shutdown
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			fatal("Failed to start server", "error", err)
 		}
 	}()
 
-	log.Printf("Server started on port %s", port)
+	build := buildinfo.Get()
+	slog.Info("Server started", "port", cfg.Port, "version", build.Version, "commit", build.Commit)
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
+
+	// Fail readiness probes first, so load balancers stop sending requests
+	// before the server stops accepting them
+	healthHandler.ShutDown()
+	time.Sleep(cfg.ShutdownDrain)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		slog.Warn("Requests still running at shutdown were cut off", "error", err)
+		srv.Close()
 	}
 
-	log.Println("Server exited")
+	stopBackground()
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Background workers did not stop in time")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+
+	slog.Info("Server exited")
 }
 
+// shutdownTimeout bounds waiting for requests in flight and background
+// workers after the drain. With the default SHUTDOWN_DRAIN of 3s it fits
+// in Cloud Run's 10 second grace period after SIGTERM.
+const shutdownTimeout = 5 * time.Second
+
+// fatal logs msg as an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
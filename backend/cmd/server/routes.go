@@ -0,0 +1,149 @@
+package main
+
+import (
+	"appdirect-workshop-backend/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeHandlers holds the handlers of every route. The OpenAPI document in
+// internal/openapi must list the same routes; routes_test.go checks it.
+type routeHandlers struct {
+	attendees *handlers.AttendeeHandler
+	speakers  *handlers.SpeakerHandler
+	sessions  *handlers.SessionHandler
+	admin     *handlers.AdminHandler
+	media     *handlers.MediaHandler
+	feedback  *handlers.FeedbackHandler
+	questions *handlers.QuestionHandler
+	polls     *handlers.PollHandler
+	backup    *handlers.BackupHandler
+	trash     *handlers.TrashHandler
+	webhooks  *handlers.WebhookHandler
+	health    *handlers.HealthHandler
+	jobs      *handlers.JobHandler
+	docs      *handlers.DocsHandler
+	adminAuth gin.HandlerFunc
+	// metrics serves /metrics, guarded by its own token
+	metrics gin.HandlerFunc
+	// idempotent replays responses to retried create requests
+	idempotent gin.HandlerFunc
+	// invalidate drops cached public reads after admin writes
+	invalidate gin.HandlerFunc
+}
+
+func registerRoutes(router *gin.Engine, h routeHandlers) {
+	// Health checks
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+	router.GET("/livez", h.health.Live)
+	router.GET("/readyz", h.health.Ready)
+	router.GET("/metrics", h.metrics)
+
+	// Public API routes
+	api := router.Group("/api")
+	{
+		// API documentation
+		api.GET("/openapi.json", h.docs.GetSpec)
+		api.GET("/docs", h.docs.GetDocs)
+
+		// Sessions
+		api.GET("/sessions", h.sessions.GetAllSessions)
+		api.GET("/sessions/:id", h.sessions.GetSession)
+		api.POST("/sessions/:id/feedback", h.idempotent, h.feedback.SubmitFeedback)
+
+		// Live Q&A
+		api.GET("/sessions/:id/questions", h.questions.GetQuestions)
+		api.GET("/sessions/:id/questions/stream", h.questions.StreamQuestions)
+		api.POST("/sessions/:id/questions", h.idempotent, h.questions.AskQuestion)
+		api.POST("/sessions/:id/questions/:questionId/upvote", h.questions.UpvoteQuestion)
+
+		// Live polls
+		api.GET("/sessions/:id/polls", h.polls.GetPolls)
+		api.GET("/sessions/:id/polls/stream", h.polls.StreamPolls)
+		api.POST("/sessions/:id/polls/:pollId/votes", h.idempotent, h.polls.Vote)
+
+		// Speakers
+		api.GET("/speakers", h.speakers.GetAllSpeakers)
+		api.GET("/speakers/:id", h.speakers.GetSpeaker)
+
+		// Media
+		api.GET("/media/*key", h.media.ServeMedia)
+
+		// Attendees
+		api.GET("/attendees/count", h.attendees.GetAttendeeCount)
+		api.GET("/attendees/count/stream", h.attendees.StreamAttendeeCount)
+		api.POST("/attendees", h.idempotent, h.attendees.RegisterAttendee)
+	}
+
+	// Admin API routes (password protected)
+	admin := router.Group("/api/admin")
+	admin.Use(h.adminAuth, h.invalidate)
+	{
+		// Attendees
+		admin.GET("/attendees", h.attendees.GetAllAttendees)
+		admin.GET("/attendees/:id", h.attendees.GetAttendee)
+		admin.DELETE("/attendees/:id", h.attendees.DeleteAttendee)
+
+		// Speakers
+		admin.GET("/speakers", h.speakers.ListSpeakers)
+		admin.POST("/speakers", h.idempotent, h.speakers.CreateSpeaker)
+		admin.PUT("/speakers/:id", h.speakers.UpdateSpeaker)
+		admin.PATCH("/speakers/:id", h.speakers.PatchSpeaker)
+		admin.DELETE("/speakers/:id", h.speakers.DeleteSpeaker)
+		admin.POST("/speakers/:id/photo", h.speakers.UploadSpeakerPhoto)
+		admin.DELETE("/speakers/:id/photo", h.speakers.DeleteSpeakerPhoto)
+
+		// Sessions
+		admin.GET("/sessions", h.sessions.ListSessions)
+		admin.POST("/sessions", h.idempotent, h.sessions.CreateSession)
+		admin.PUT("/sessions/:id", h.sessions.UpdateSession)
+		admin.PATCH("/sessions/:id", h.sessions.PatchSession)
+		admin.DELETE("/sessions/:id", h.sessions.DeleteSession)
+		admin.GET("/sessions/:id/questions", h.questions.GetAllQuestions)
+		admin.PATCH("/sessions/:id/questions/:questionId", h.questions.ModerateQuestion)
+		admin.GET("/sessions/:id/polls", h.polls.GetAllPolls)
+		admin.POST("/sessions/:id/polls", h.idempotent, h.polls.CreatePoll)
+		admin.POST("/sessions/:id/polls/:pollId/open", h.polls.OpenPoll)
+		admin.POST("/sessions/:id/polls/:pollId/close", h.polls.ClosePoll)
+		admin.DELETE("/sessions/:id/polls/:pollId", h.polls.DeletePoll)
+
+		// Analytics
+		admin.GET("/analytics/designation", h.admin.GetDesignationBreakdown)
+		admin.GET("/analytics/polls", h.admin.GetPollResults)
+		admin.POST("/analytics/recompute", h.admin.RecomputeCounters)
+		admin.GET("/analytics/snapshots", h.admin.GetAnalyticsSnapshots)
+
+		// Trash
+		admin.GET("/trash/:kind", h.trash.GetTrash)
+		admin.POST("/trash/:kind/:id/restore", h.trash.RestoreFromTrash)
+		admin.DELETE("/trash/:kind/:id", h.trash.PurgeFromTrash)
+
+		// Backup
+		admin.GET("/backup", h.backup.ExportEvent)
+		admin.POST("/restore", h.backup.RestoreEvent)
+
+		// Feedback
+		admin.GET("/feedback/sessions", h.feedback.GetSessionSummaries)
+		admin.GET("/feedback/sessions/:id", h.feedback.GetSessionSummary)
+		admin.GET("/feedback/speakers", h.feedback.GetSpeakerSummaries)
+		admin.GET("/feedback/speakers/:id", h.feedback.GetSpeakerSummary)
+		admin.GET("/feedback/export", h.feedback.ExportFeedback)
+
+		// Webhooks
+		admin.GET("/webhooks", h.webhooks.GetWebhooks)
+		admin.POST("/webhooks", h.idempotent, h.webhooks.CreateWebhook)
+		admin.GET("/webhooks/:id", h.webhooks.GetWebhook)
+		admin.PUT("/webhooks/:id", h.webhooks.UpdateWebhook)
+		admin.DELETE("/webhooks/:id", h.webhooks.DeleteWebhook)
+		admin.POST("/webhooks/:id/enable", h.webhooks.EnableWebhook)
+		admin.POST("/webhooks/:id/disable", h.webhooks.DisableWebhook)
+		admin.GET("/webhooks/:id/deliveries", h.webhooks.GetDeliveries)
+		admin.POST("/webhooks/:id/deliveries/:deliveryId/replay", h.webhooks.ReplayDelivery)
+
+		// Scheduled jobs
+		admin.GET("/jobs", h.jobs.GetJobs)
+		admin.POST("/jobs/:name/run", h.jobs.RunJob)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"appdirect-workshop-backend/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRoutesMatchOpenAPI fails when a route is registered without being in
+// the OpenAPI document, or documented without being registered
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerRoutes(router, routeHandlers{adminAuth: func(c *gin.Context) {}})
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+openapi.Path(route.Path)] = true
+	}
+
+	raw, err := openapi.JSON()
+	if err != nil {
+		t.Fatalf("encoding OpenAPI document: %v", err)
+	}
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatalf("decoding OpenAPI document: %v", err)
+	}
+	documented := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for _, route := range sortedKeys(registered) {
+		if !documented[route] {
+			t.Errorf("route %s is not in the OpenAPI document", route)
+		}
+	}
+	for _, route := range sortedKeys(documented) {
+		if !registered[route] {
+			t.Errorf("OpenAPI document lists %s, which is not registered", route)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"golang.org/x/term"
+)
+
+const minAdminPasswordLength = 12
+
+func createAdmin(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("admins create")
+	email := fs.String("email", "", "admin email (required)")
+	name := fs.String("name", "", "admin display name")
+	parseFlags(fs, "admins create", args)
+
+	if _, err := mail.ParseAddress(*email); err != nil || strings.ContainsAny(*email, "<>/") {
+		return fmt.Errorf("invalid email %q", *email)
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return fmt.Errorf("reading password: %w", err)
+	}
+	if len(password) < minAdminPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minAdminPasswordLength)
+	}
+
+	admin := &models.AdminUser{Email: *email, Name: *name}
+	if err := a.repo.CreateAdminUser(ctx, admin, password); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			return fmt.Errorf("admin %s already exists", admin.Email)
+		}
+		return err
+	}
+	return a.out.message(admin, "Created admin %s", admin.Email)
+}
+
+// readPassword prompts for a password on a terminal without echoing it,
+// or reads one line from stdin when it is piped
+func readPassword() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func listAdmins(ctx context.Context, a *app, args []string) error {
+	parseFlags(newFlags("admins list"), "admins list", args)
+
+	admins, err := a.repo.GetAdminUsers(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(admins))
+	for _, admin := range admins {
+		rows = append(rows, []string{admin.Email, admin.Name, formatTime(&admin.CreatedAt)})
+	}
+	return a.out.print(admins, []string{"EMAIL", "NAME", "CREATED"}, rows)
+}
+
+func deleteAdmin(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("admins delete")
+	email := fs.String("email", "", "admin email (required)")
+	parseFlags(fs, "admins delete", args)
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	if err := a.repo.DeleteAdminUser(ctx, *email); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("admin %s not found", *email)
+		}
+		return err
+	}
+	return a.out.message(map[string]string{"deleted": *email}, "Deleted admin %s", *email)
+}
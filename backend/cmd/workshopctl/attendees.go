@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/export"
+	"appdirect-workshop-backend/internal/models"
+)
+
+// attendees returns the event's attendees in registration order
+func (a *app) attendees(ctx context.Context) ([]models.Attendee, error) {
+	attendees, err := a.repo.GetAllAttendees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(attendees, func(i, j int) bool {
+		return attendees[i].RegisteredAt.Before(attendees[j].RegisteredAt)
+	})
+	return attendees, nil
+}
+
+func listAttendees(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("attendees list")
+	designation := fs.String("designation", "", "only attendees with this designation")
+	search := fs.String("search", "", "only attendees whose name or email contains this text")
+	parseFlags(fs, "attendees list", args)
+
+	attendees, err := a.attendees(ctx)
+	if err != nil {
+		return err
+	}
+
+	needle := strings.ToLower(*search)
+	matched := make([]models.Attendee, 0, len(attendees))
+	rows := make([][]string, 0, len(attendees))
+	for _, attendee := range attendees {
+		if *designation != "" && !strings.EqualFold(attendee.Designation, *designation) {
+			continue
+		}
+		if needle != "" && !strings.Contains(strings.ToLower(attendee.Name+" "+attendee.Email), needle) {
+			continue
+		}
+		matched = append(matched, attendee)
+		rows = append(rows, []string{attendee.ID, attendee.Name, attendee.Email, attendee.Designation, formatTime(&attendee.RegisteredAt)})
+	}
+	return a.out.print(matched, []string{"ID", "NAME", "EMAIL", "DESIGNATION", "REGISTERED"}, rows)
+}
+
+func exportAttendees(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("attendees export")
+	format := fs.String("format", "csv", "file format: csv or json")
+	file := fs.String("o", "", "file to write (default stdout)")
+	parseFlags(fs, "attendees export", args)
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	attendees, err := a.attendees(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(attendees)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "email", "designation", "registered_at"})
+	for _, attendee := range attendees {
+		cw.Write([]string{
+			attendee.ID,
+			export.CSVSafe(attendee.Name),
+			export.CSVSafe(attendee.Email),
+			export.CSVSafe(attendee.Designation),
+			attendee.RegisteredAt.UTC().Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
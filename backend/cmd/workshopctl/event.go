@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+func recomputeCounters(ctx context.Context, a *app, args []string) error {
+	parseFlags(newFlags("counters recompute"), "counters recompute", args)
+
+	count, err := a.repo.RecomputeCounters(ctx)
+	if err != nil {
+		return err
+	}
+	return a.out.message(map[string]int{"count": count},
+		"Counters recomputed for event %s: %d attendees", a.repo.EventID(), count)
+}
+
+func migrate(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("migrate")
+	statusOnly := fs.Bool("status", false, "list migrations without running them")
+	parseFlags(fs, "migrate", args)
+
+	if !*statusOnly {
+		applied, err := a.repo.RunMigrations(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(os.Stderr, "Applied %s\n", m.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	statuses, err := a.repo.GetMigrations(ctx)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(statuses))
+	for _, m := range statuses {
+		rows = append(rows, []string{m.ID, formatTime(m.AppliedAt), m.Description})
+	}
+	return a.out.print(statuses, []string{"MIGRATION", "APPLIED", "DESCRIPTION"}, rows)
+}
+
+func cloneEvent(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("clone")
+	to := fs.String("to", "", "event ID to copy the event into (required)")
+	onConflict := fs.String("on-conflict", models.ConflictFail, "what to do with existing documents: fail, skip or overwrite")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	parseFlags(fs, "clone", args)
+	if !repository.ValidEventID(*to) || *to == a.repo.EventID() {
+		return fmt.Errorf("invalid target event ID %q", *to)
+	}
+
+	archive, err := a.repo.ExportEvent(ctx)
+	if err != nil {
+		return err
+	}
+	return a.restore(ctx, a.repo.ForEvent(*to), archive, *onConflict, *dryRun)
+}
+
+func exportBackup(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("backup export")
+	file := fs.String("o", "", "archive file to write (default stdout)")
+	parseFlags(fs, "backup export", args)
+
+	archive, err := a.repo.ExportEvent(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported event %s\n", archive.EventID)
+	return nil
+}
+
+func restoreBackup(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("backup restore")
+	onConflict := fs.String("on-conflict", models.ConflictFail, "what to do with existing documents: fail, skip or overwrite")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	parseFlags(fs, "backup restore", args)
+	if fs.NArg() != 1 {
+		return errors.New("exactly one archive file is required")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var archive models.EventArchive
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+	if err := dec.Decode(&archive); err != nil {
+		return fmt.Errorf("invalid archive: %w", err)
+	}
+	return a.restore(ctx, a.repo, &archive, *onConflict, *dryRun)
+}
+
+// restore writes archive into the event of target and prints the report
+func (a *app) restore(ctx context.Context, target *repository.Repository, archive *models.EventArchive, onConflict string, dryRun bool) error {
+	report, err := target.RestoreEvent(ctx, archive, onConflict, dryRun)
+	if report != nil {
+		rows := [][]string{
+			{"created", strconv.Itoa(report.Created)},
+			{"overwritten", strconv.Itoa(report.Overwritten)},
+			{"skipped", strconv.Itoa(report.Skipped)},
+			{"conflicts", strconv.Itoa(len(report.Conflicts))},
+		}
+		if printErr := a.out.print(report, []string{"EVENT " + report.EventID, dryRunLabel(dryRun)}, rows); printErr != nil {
+			return printErr
+		}
+	}
+	if errors.Is(err, repository.ErrInvalidArchive) && report != nil {
+		return fmt.Errorf("invalid archive:\n  %s", strings.Join(report.Errors, "\n  "))
+	}
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return errors.New("documents already exist; use -on-conflict skip or overwrite")
+	}
+	return err
+}
+
+func dryRunLabel(dryRun bool) string {
+	if dryRun {
+		return "DRY RUN"
+	}
+	return "RESTORED"
+}
@@ -0,0 +1,136 @@
+// Command workshopctl manages an event directly through the repository,
+// without going through the HTTP API.
+//
+//	go run ./cmd/workshopctl [-config file] [-event ID] [-output table|json] <command> [flags]
+//
+// Run it without a command for the list of commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"appdirect-workshop-backend/internal/config"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+// app is what every command runs with
+type app struct {
+	repo *repository.Repository
+	out  *output
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, a *app, args []string) error
+}
+
+// commands is filled in by init, as the commands' usage refers back to it
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"seed":               {"seed [-attendees N] [-date YYYY-MM-DD] [-seed N] [-force]", seed},
+		"attendees list":     {"attendees list [-designation D] [-search S]", listAttendees},
+		"attendees export":   {"attendees export [-format csv|json] [-o file]", exportAttendees},
+		"admins create":      {"admins create -email E -name N  (password read from stdin)", createAdmin},
+		"admins list":        {"admins list", listAdmins},
+		"admins delete":      {"admins delete -email E", deleteAdmin},
+		"counters recompute": {"counters recompute", recomputeCounters},
+		"migrate":            {"migrate [-status]", migrate},
+		"clone":              {"clone -to ID [-on-conflict fail|skip|overwrite] [-dry-run]", cloneEvent},
+		"backup export":      {"backup export [-o file]", exportBackup},
+		"backup restore":     {"backup restore [-on-conflict fail|skip|overwrite] [-dry-run] file", restoreBackup},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: workshopctl [-config file] [-event ID] [-output table|json] <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nflags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	eventID := flag.String("event", "", "event ID (default FIRESTORE_SUBCOLLECTION_ID)")
+	format := flag.String("output", "table", "output format: table or json")
+	configFile := flag.String("config", "", "YAML or TOML config file (default $CONFIG_FILE)")
+	flag.Usage = usage
+	flag.Parse()
+
+	name, args, ok := lookup(flag.Args())
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if *format != "table" && *format != "json" {
+		log.Fatalf("unknown output format %q", *format)
+	}
+
+	cfg, err := config.Read(*configFile)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	projectID, subDocID := cfg.ProjectID, cfg.EventID
+	if *eventID != "" {
+		subDocID = *eventID
+	}
+	if projectID == "" || subDocID == "" {
+		log.Fatal("FIRESTORE_PROJECT_ID and FIRESTORE_SUBCOLLECTION_ID (or -event) are required")
+	}
+	if !repository.ValidEventID(subDocID) {
+		log.Fatalf("invalid event ID %q", subDocID)
+	}
+
+	ctx := context.Background()
+	repo, err := repository.NewRepository(ctx, projectID, subDocID, cfg.ServiceAccountPath)
+	if err != nil {
+		log.Fatalf("Failed to initialize repository: %v", err)
+	}
+
+	err = commands[name].run(ctx, &app{repo: repo, out: &output{json: *format == "json"}}, args)
+	repo.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+}
+
+// lookup finds the command named by the first one or two arguments
+func lookup(args []string) (string, []string, bool) {
+	if len(args) >= 2 {
+		if name := args[0] + " " + args[1]; commands[name].run != nil {
+			return name, args[2:], true
+		}
+	}
+	if len(args) >= 1 && commands[args[0]].run != nil {
+		return args[0], args[1:], true
+	}
+	return "", nil, false
+}
+
+// parseFlags parses a command's flags, printing its usage line on errors
+func parseFlags(fs *flag.FlagSet, name string, args []string) {
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: workshopctl "+commands[name].usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+}
+
+func newFlags(name string) *flag.FlagSet {
+	return flag.NewFlagSet(strings.ReplaceAll(name, " ", "-"), flag.ExitOnError)
+}
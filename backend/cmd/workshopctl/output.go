@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// output prints command results as an aligned table or as JSON
+type output struct {
+	json bool
+}
+
+// print writes v as JSON, or headers and rows as a table
+func (o *output) print(v interface{}, headers []string, rows [][]string) error {
+	if o.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// message prints a one-line result, or v as JSON
+func (o *output) message(v interface{}, format string, args ...interface{}) error {
+	if o.json {
+		return o.print(v, nil, nil)
+	}
+	_, err := fmt.Printf(format+"\n", args...)
+	return err
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"appdirect-workshop-backend/internal/fixtures"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+var _ fixtures.Store = (*repository.Repository)(nil)
+
+func seed(ctx context.Context, a *app, args []string) error {
+	fs := newFlags("seed")
+	attendeeCount := fs.Int("attendees", 25, "number of synthetic attendees to register")
+	date := fs.String("date", fixtures.ReferenceDate.Format("2006-01-02"), "day the sessions take place, in UTC")
+	seedValue := fs.Int64("seed", fixtures.DefaultSeed, "seed for the generated data; the same seed and date give the same data")
+	force := fs.Bool("force", false, "seed even if the event already has speakers or sessions; entities that exist are skipped")
+	parseFlags(fs, "seed", args)
+
+	day, err := time.ParseInLocation("2006-01-02", *date, time.UTC)
+	if err != nil {
+		return fmt.Errorf("invalid -date: %w", err)
+	}
+	if *attendeeCount < 0 {
+		return errors.New("-attendees must not be negative")
+	}
+
+	if !*force {
+		speakers, err := a.repo.GetAllSpeakers(ctx)
+		if err != nil {
+			return err
+		}
+		sessions, err := a.repo.GetAllSessions(ctx)
+		if err != nil {
+			return err
+		}
+		if len(speakers) > 0 || len(sessions) > 0 {
+			return fmt.Errorf("event %s already has speakers or sessions; use -force to seed anyway", a.repo.EventID())
+		}
+	}
+
+	set, err := fixtures.Load(ctx, a.repo, fixtures.Options{Attendees: *attendeeCount, Seed: *seedValue, Date: day})
+	if err != nil {
+		return err
+	}
+
+	summary := map[string]int{"speakers": len(set.Speakers), "sessions": len(set.Sessions), "attendees": len(set.Attendees), "existing": set.Existing}
+	return a.out.message(summary, "Seeded event %s with %d speakers, %d sessions and %d attendees (%d already existed)",
+		a.repo.EventID(), len(set.Speakers), len(set.Sessions), len(set.Attendees), set.Existing)
+}
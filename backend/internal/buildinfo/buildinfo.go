@@ -0,0 +1,45 @@
+// Package buildinfo identifies the build of the running server. Version
+// and Commit are set when building, e.g.
+//
+//	go build -ldflags "-X appdirect-workshop-backend/internal/buildinfo.Version=1.4.0 -X appdirect-workshop-backend/internal/buildinfo.Commit=$(git rev-parse --short HEAD)"
+//
+// Without them the commit is taken from the VCS information Go embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"appdirect-workshop-backend/internal/models"
+)
+
+var (
+	// Version is the release version of the build
+	Version = "dev"
+	// Commit is the revision the build was made from
+	Commit = ""
+)
+
+// Get returns the build info of the running server
+func Get() models.BuildInfo {
+	info := models.BuildInfo{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if build, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range build.Settings {
+				if setting.Key == "vcs.revision" {
+					info.Commit = setting.Value
+				}
+			}
+		}
+	}
+	return info
+}
+
+// Short abbreviates a commit hash to seven characters, as git does
+func Short(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
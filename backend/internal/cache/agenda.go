@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+// Agenda caches the public session and speaker lists in front of the
+// repository. Each server instance has its own copy: writes through this
+// instance invalidate it at once, other instances catch up within the TTL.
+type Agenda struct {
+	sessions *Cache[[]models.SessionWithSpeakers]
+	speakers *Cache[[]models.Speaker]
+	maxAge   time.Duration
+}
+
+// NewAgenda returns an agenda cache whose lists are reloaded after ttl.
+// HTTP caches may reuse the lists for maxAge without revalidating them.
+func NewAgenda(repo *repository.Repository, ttl, maxAge time.Duration) *Agenda {
+	return &Agenda{
+		sessions: New("sessions", ttl, repo.GetSessionsWithSpeakers),
+		speakers: New("speakers", ttl, repo.GetAllSpeakers),
+		maxAge:   maxAge,
+	}
+}
+
+// MaxAge is how long HTTP caches may reuse the lists without revalidating
+func (a *Agenda) MaxAge() time.Duration {
+	return a.maxAge
+}
+
+// Sessions returns every session with its speakers
+func (a *Agenda) Sessions(ctx context.Context) (*Entry[[]models.SessionWithSpeakers], error) {
+	return a.sessions.Get(ctx)
+}
+
+// Speakers returns every speaker
+func (a *Agenda) Speakers(ctx context.Context) (*Entry[[]models.Speaker], error) {
+	return a.speakers.Get(ctx)
+}
+
+// Invalidate drops both lists, after sessions or speakers were written
+func (a *Agenda) Invalidate() {
+	a.sessions.Invalidate()
+	a.speakers.Invalidate()
+}
@@ -0,0 +1,105 @@
+// Package cache keeps read-through copies of data that is read far more
+// often than it changes, such as the public agenda. Entries expire after a
+// TTL and are dropped as soon as the data is known to have changed.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/metrics"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Entry is a cached value with the validator HTTP caches use to check
+// whether their copy is current. The value is shared; callers must not
+// modify it.
+type Entry[T any] struct {
+	Value T
+	// ETag is a strong entity tag of the value's JSON encoding, so every
+	// instance gives the same value the same tag
+	ETag string
+}
+
+// Cache holds the value returned by a load function until it expires or
+// is invalidated. Concurrent misses share a single load.
+type Cache[T any] struct {
+	name string
+	ttl  time.Duration
+	load func(ctx context.Context) (T, error)
+
+	mu         sync.Mutex
+	entry      *Entry[T]
+	expires    time.Time
+	generation uint64
+	loads      singleflight.Group
+}
+
+// New returns a cache, named in metrics, holding the value of load for ttl
+func New[T any](name string, ttl time.Duration, load func(ctx context.Context) (T, error)) *Cache[T] {
+	return &Cache[T]{name: name, ttl: ttl, load: load}
+}
+
+// Get returns the cached entry, loading it if it expired or was
+// invalidated
+func (c *Cache[T]) Get(ctx context.Context) (*Entry[T], error) {
+	c.mu.Lock()
+	entry, generation := c.entry, c.generation
+	fresh := entry != nil && time.Now().Before(c.expires)
+	c.mu.Unlock()
+	metrics.ObserveCache(c.name, fresh)
+	if fresh {
+		return entry, nil
+	}
+
+	// Loads started before an invalidation are not shared with callers
+	// arriving after it, and a load shared by several requests is not
+	// cancelled with the one that started it
+	v, err, _ := c.loads.Do(strconv.FormatUint(generation, 10), func() (interface{}, error) {
+		value, err := c.load(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return c.store(generation, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Entry[T]), nil
+}
+
+// store caches value loaded in generation, unless the cache was
+// invalidated meanwhile, and returns its entry
+func (c *Cache[T]) store(generation uint64, value T) (*Entry[T], error) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	entry := &Entry[T]{
+		Value: value,
+		ETag:  `"` + hex.EncodeToString(sum[:16]) + `"`,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation == c.generation {
+		c.entry = entry
+		c.expires = time.Now().Add(c.ttl)
+	}
+	return entry, nil
+}
+
+// Invalidate makes the next Get load the value again
+func (c *Cache[T]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires = time.Time{}
+	c.generation++
+}
@@ -0,0 +1,448 @@
+// Package config loads the server configuration. Every setting has a
+// default, which is overridden in turn by a YAML or TOML config file, the
+// environment (including a .env file) and command-line flags.
+//
+// A setting is named by its key in config files, e.g. cors_origins; its
+// flag is the key with dashes, e.g. -cors-origins, and its environment
+// variable is given by its env tag, e.g. CORS_ORIGIN. Lists are written
+// comma-separated, or as lists in config files.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of the server
+type Config struct {
+	// Firestore
+	ProjectID          string `key:"firestore_project_id" env:"FIRESTORE_PROJECT_ID" usage:"Firebase project ID (required)"`
+	EventID            string `key:"firestore_subcollection_id" env:"FIRESTORE_SUBCOLLECTION_ID" usage:"ID of the event whose data is served (required)"`
+	ServiceAccountPath string `key:"firebase_service_account_path" env:"FIREBASE_SERVICE_ACCOUNT_PATH" usage:"service account JSON file; application default credentials when empty"`
+
+	// HTTP
+	Port          string        `key:"port" env:"PORT" default:"8080" usage:"port to listen on"`
+	CORSOrigins   []string      `key:"cors_origins" env:"CORS_ORIGIN" default:"http://localhost:3000" usage:"origins allowed to call the API, comma-separated"`
+	GinMode       string        `key:"gin_mode" env:"GIN_MODE" default:"debug" usage:"debug, release or test"`
+	ShutdownDrain time.Duration `key:"shutdown_drain" env:"SHUTDOWN_DRAIN" default:"3s" usage:"how long /readyz fails before the server stops on SIGTERM"`
+
+	// Admin
+	AdminPassword string `key:"admin_password" env:"ADMIN_PASSWORD" secret:"true" usage:"shared admin password (required)"`
+
+	// Media
+	MediaDir      string `key:"media_storage_dir" env:"MEDIA_STORAGE_DIR" default:"./data/media" usage:"directory for uploaded media"`
+	MediaBaseURL  string `key:"media_base_url" env:"MEDIA_BASE_URL" usage:"absolute URL media links start with; relative links when empty"`
+	PhotoMaxBytes int64  `key:"photo_max_bytes" env:"PHOTO_MAX_BYTES" default:"5242880" usage:"largest accepted speaker photo, in bytes"`
+
+	// Caching
+	AgendaCacheTTL time.Duration `key:"agenda_cache_ttl" env:"AGENDA_CACHE_TTL" default:"1m" usage:"how long the public session and speaker lists are cached; 0 disables the cache"`
+	AgendaMaxAge   time.Duration `key:"agenda_max_age" env:"AGENDA_MAX_AGE" default:"0s" usage:"how long browsers and CDNs may reuse the lists without revalidating"`
+
+	// Retention
+	TrashRetention time.Duration `key:"trash_retention" env:"TRASH_RETENTION" default:"720h" usage:"how long deleted items stay in the trash"`
+	IdempotencyTTL time.Duration `key:"idempotency_ttl" env:"IDEMPOTENCY_TTL" default:"24h" usage:"how long responses are kept for Idempotency-Key retries"`
+
+	// Email
+	ReminderLead time.Duration `key:"reminder_lead" env:"REMINDER_LEAD" default:"1h" usage:"how long before a session attendees are reminded"`
+	SMTPAddr     string        `key:"smtp_addr" env:"SMTP_ADDR" usage:"SMTP server as host:port; emails are logged when empty"`
+	SMTPUsername string        `key:"smtp_username" env:"SMTP_USERNAME" usage:"SMTP username"`
+	SMTPPassword string        `key:"smtp_password" env:"SMTP_PASSWORD" secret:"true" usage:"SMTP password"`
+	MailFrom     string        `key:"mail_from" env:"MAIL_FROM" usage:"sender address of emails (required with smtp_addr)"`
+
+	// Observability
+	MetricsToken   string `key:"metrics_token" env:"METRICS_TOKEN" secret:"true" usage:"bearer token required to scrape /metrics; required when gin_mode is release, open when empty otherwise"`
+	TracesExporter string `key:"traces_exporter" env:"TRACES_EXPORTER" default:"none" usage:"none, otlp or stdout"`
+	LogLevel       string `key:"log_level" env:"LOG_LEVEL" default:"info" usage:"debug, info, warn or error"`
+	LogFormat      string `key:"log_format" env:"LOG_FORMAT" default:"json" usage:"json or text"`
+
+	// sources records where each setting came from, by key
+	sources map[string]string
+}
+
+// Sources of settings
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// setting is a field of Config with its tags
+type setting struct {
+	index  int
+	key    string
+	env    string
+	def    string
+	usage  string
+	secret bool
+}
+
+var settings = func() []setting {
+	t := reflect.TypeOf(Config{})
+	var list []setting
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("key")
+		if key == "" {
+			continue
+		}
+		list = append(list, setting{
+			index:  i,
+			key:    key,
+			env:    f.Tag.Get("env"),
+			def:    f.Tag.Get("default"),
+			usage:  f.Tag.Get("usage"),
+			secret: f.Tag.Get("secret") == "true",
+		})
+	}
+	return list
+}()
+
+// Read returns the configuration from the defaults, the config file at
+// path, or at CONFIG_FILE when path is empty, and the environment. The
+// environment is first extended with the .env file at ENV_FILE, or in the
+// working directory if there is one. A variable that is set but empty
+// empties its setting, e.g. clearing a value from the config file. The
+// configuration is not validated.
+func Read(path string) (*Config, error) {
+	if err := loadDotEnv(); err != nil {
+		return nil, err
+	}
+
+	c := &Config{sources: make(map[string]string)}
+	for _, s := range settings {
+		if err := c.set(s, s.def, SourceDefault); err != nil {
+			return nil, fmt.Errorf("default of %s: %w", s.key, err)
+		}
+	}
+
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := c.set(s, v, SourceEnv); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Load returns the validated configuration of the server: Read with the
+// file given by -config, overridden by the flags in args. It also returns
+// whether -print-config was given.
+func Load(name string, args []string) (c *Config, printOnly bool, err error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("config", "", "YAML (.yaml, .yml) or TOML (.toml) config file (default $CONFIG_FILE)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration, with secrets redacted, and exit")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		usage := s.usage + " ($" + s.env + ")"
+		values[s.key] = fs.String(flagName(s.key), s.def, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	c, err = Read(*path)
+	if err != nil {
+		return nil, false, err
+	}
+	var errs []error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if flagName(s.key) == f.Name {
+				if err := c.set(s, *values[s.key], SourceFlag); err != nil {
+					errs = append(errs, fmt.Errorf("-%s: %w", f.Name, err))
+				}
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, false, err
+	}
+	if *printConfig {
+		return c, true, nil
+	}
+	return c, false, c.Validate()
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// loadDotEnv adds the variables of the .env file to the environment,
+// without overriding variables that are already set
+func loadDotEnv() error {
+	path := os.Getenv("ENV_FILE")
+	if path == "" {
+		if _, err := os.Stat(".env"); err != nil {
+			return nil
+		}
+		path = ".env"
+	}
+	if err := godotenv.Load(path); err != nil {
+		return fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	return nil
+}
+
+// readFile sets the settings found in a YAML or TOML file
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	values := make(map[string]interface{})
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	var errs []error
+	for key, value := range values {
+		s, ok := lookup(key)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+			continue
+		}
+		if err := c.set(s, fileValue(value), SourceFile); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func lookup(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// fileValue turns a value decoded from a config file into the text form
+// of settings
+func fileValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set parses raw into the field of s
+func (c *Config) set(s setting, raw string, source string) error {
+	field := reflect.ValueOf(c).Elem().Field(s.index)
+	raw = strings.TrimSpace(raw)
+	switch {
+	case field.Type() == durationType:
+		d := time.Duration(0)
+		if raw != "" {
+			var err error
+			if d, err = time.ParseDuration(raw); err != nil {
+				return fmt.Errorf("%q is not a duration such as 90s, 15m or 24h", raw)
+			}
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.Int64:
+		n := int64(0)
+		if raw != "" {
+			var err error
+			if n, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				return fmt.Errorf("%q is not a whole number", raw)
+			}
+		}
+		field.SetInt(n)
+	case field.Kind() == reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		field.SetString(raw)
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+// Validate reports every setting that is missing or invalid
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]interface{}{key}, args...)...))
+	}
+
+	if c.ProjectID == "" {
+		fail("firestore_project_id", "is required")
+	}
+	if c.EventID == "" {
+		fail("firestore_subcollection_id", "is required")
+	}
+	if c.AdminPassword == "" {
+		fail("admin_password", "is required")
+	}
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		fail("port", "%q is not a port number", c.Port)
+	}
+	if len(c.CORSOrigins) == 0 {
+		fail("cors_origins", "at least one origin is required")
+	}
+	for _, origin := range c.CORSOrigins {
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			fail("cors_origins", "%q is not an origin such as https://example.com", origin)
+		}
+	}
+	oneOf(fail, "gin_mode", c.GinMode, "debug", "release", "test")
+	if c.GinMode == "release" && c.MetricsToken == "" {
+		fail("metrics_token", "is required when gin_mode is release")
+	}
+	if c.ShutdownDrain < 0 {
+		fail("shutdown_drain", "must not be negative")
+	}
+
+	if c.MediaDir == "" {
+		fail("media_storage_dir", "is required")
+	}
+	if c.MediaBaseURL != "" {
+		if u, err := url.Parse(c.MediaBaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("media_base_url", "%q is not an absolute http(s) URL", c.MediaBaseURL)
+		}
+	}
+	if c.PhotoMaxBytes <= 0 {
+		fail("photo_max_bytes", "must be positive")
+	}
+
+	if c.AgendaCacheTTL < 0 {
+		fail("agenda_cache_ttl", "must not be negative")
+	}
+	if c.AgendaMaxAge < 0 {
+		fail("agenda_max_age", "must not be negative")
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"trash_retention", c.TrashRetention},
+		{"idempotency_ttl", c.IdempotencyTTL},
+		{"reminder_lead", c.ReminderLead},
+	} {
+		if d.value <= 0 {
+			fail(d.key, "must be a positive duration")
+		}
+	}
+
+	if c.SMTPAddr != "" {
+		if _, _, err := net.SplitHostPort(c.SMTPAddr); err != nil {
+			fail("smtp_addr", "%q is not a host:port address", c.SMTPAddr)
+		}
+		if c.MailFrom == "" {
+			fail("mail_from", "is required when smtp_addr is set")
+		}
+	}
+	if c.MailFrom != "" {
+		if _, err := mail.ParseAddress(c.MailFrom); err != nil {
+			fail("mail_from", "%q is not an email address", c.MailFrom)
+		}
+	}
+
+	oneOf(fail, "traces_exporter", c.TracesExporter, "none", "otlp", "stdout")
+	oneOf(fail, "log_level", strings.ToLower(c.LogLevel), "debug", "info", "warn", "error")
+	oneOf(fail, "log_format", c.LogFormat, "json", "text")
+	return errors.Join(errs...)
+}
+
+func oneOf(fail func(key, format string, args ...interface{}), key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	fail(key, "%q must be one of %s", value, strings.Join(allowed, ", "))
+}
+
+// LogValue logs the effective settings by key, with secrets redacted
+func (c *Config) LogValue() slog.Value {
+	attrs := make([]slog.Attr, len(settings))
+	for i, s := range settings {
+		attrs[i] = slog.String(s.key, c.display(s))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// display formats the value of s, hiding secrets that are set
+func (c *Config) display(s setting) string {
+	field := reflect.ValueOf(c).Elem().Field(s.index)
+	switch {
+	case s.secret:
+		if field.String() != "" {
+			return "[REDACTED]"
+		}
+		return ""
+	case field.Type() == durationType:
+		return time.Duration(field.Int()).String()
+	case field.Kind() == reflect.Slice:
+		return strings.Join(field.Interface().([]string), ",")
+	}
+	return fmt.Sprint(field.Interface())
+}
+
+// Print writes every setting with its effective value and where it came
+// from, with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.key, c.display(s), c.sources[s.key])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// isolate unsets every setting's environment variable, and the variables
+// naming config and .env files, for the duration of the test
+func isolate(t *testing.T) {
+	t.Helper()
+	names := []string{"CONFIG_FILE", "ENV_FILE"}
+	for _, s := range settings {
+		names = append(names, s.env)
+	}
+	for _, name := range names {
+		if old, ok := os.LookupEnv(name); ok {
+			os.Unsetenv(name)
+			t.Cleanup(func() { os.Setenv(name, old) })
+		}
+	}
+	// Keep any .env file in the package directory out of the test
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// required are the settings without defaults that Validate insists on
+var required = []string{"-firestore-project-id=p", "-firestore-subcollection-id=e", "-admin-password=secret"}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		env        map[string]string
+		args       []string
+		wantPort   string
+		wantSource string
+	}{
+		{"default", "", nil, nil, "8080", SourceDefault},
+		{"file over default", "port: 9000\n", nil, nil, "9000", SourceFile},
+		{"env over file", "port: 9000\n", map[string]string{"PORT": "9100"}, nil, "9100", SourceEnv},
+		{"flag over env", "port: 9000\n", map[string]string{"PORT": "9100"}, []string{"-port=9200"}, "9200", SourceFlag},
+		{"flag over file", "port: 9000\n", nil, []string{"-port=9200"}, "9200", SourceFlag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			args := append([]string{}, required...)
+			if tt.file != "" {
+				args = append(args, "-config="+writeFile(t, "config.yaml", tt.file))
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			args = append(args, tt.args...)
+
+			c, _, err := Load("server", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Port != tt.wantPort || c.sources["port"] != tt.wantSource {
+				t.Errorf("port = %s from %s, want %s from %s", c.Port, c.sources["port"], tt.wantPort, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestConfigFileFormats(t *testing.T) {
+	tests := []struct {
+		name, file, content string
+	}{
+		{"yaml list", "config.yaml", "cors_origins:\n  - https://a.example.com\n  - https://b.example.com\ntrash_retention: 48h\n"},
+		{"toml list", "config.toml", "cors_origins = [\"https://a.example.com\", \"https://b.example.com\"]\ntrash_retention = \"48h\"\n"},
+		{"comma-separated", "config.yml", "cors_origins: https://a.example.com, https://b.example.com\ntrash_retention: 48h\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			c, err := Read(writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(c.CORSOrigins, " "); got != "https://a.example.com https://b.example.com" {
+				t.Errorf("cors_origins = %q", got)
+			}
+			if c.TrashRetention != 48*time.Hour {
+				t.Errorf("trash_retention = %v, want 48h", c.TrashRetention)
+			}
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown file key", "port: 8080\nprot: 9000\n", nil, `unknown setting "prot"`},
+		{"bad file duration", "trash_retention: 30 days\n", nil, `trash_retention: "30 days" is not a duration`},
+		{"bad env duration", "", map[string]string{"IDEMPOTENCY_TTL": "24"}, `IDEMPOTENCY_TTL: "24" is not a duration`},
+		{"bad env number", "", map[string]string{"PHOTO_MAX_BYTES": "5MB"}, `PHOTO_MAX_BYTES: "5MB" is not a whole number`},
+		{"every bad variable", "", map[string]string{"REMINDER_LEAD": "soon", "AGENDA_CACHE_TTL": "often"}, "AGENDA_CACHE_TTL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			path := ""
+			if tt.file != "" {
+				path = writeFile(t, "config.yaml", tt.file)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			_, err := Read(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("unsupported file type", func(t *testing.T) {
+		isolate(t)
+		if _, err := Read(writeFile(t, "config.json", "{}")); err == nil {
+			t.Error("Read accepted a .json config file")
+		}
+	})
+	t.Run("bad flag duration", func(t *testing.T) {
+		isolate(t)
+		_, _, err := Load("server", append([]string{"-reminder-lead=1 hour"}, required...))
+		if err == nil || !strings.Contains(err.Error(), "-reminder-lead") {
+			t.Errorf("Load error = %v, want one naming -reminder-lead", err)
+		}
+	})
+}
+
+func TestEmptyEnvClearsFileSetting(t *testing.T) {
+	isolate(t)
+	path := writeFile(t, "config.yaml", "media_base_url: https://media.example.com\nsmtp_addr: smtp.example.com:587\n")
+	t.Setenv("MEDIA_BASE_URL", "")
+
+	c, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MediaBaseURL != "" || c.sources["media_base_url"] != SourceEnv {
+		t.Errorf("media_base_url = %q from %s, want empty from env", c.MediaBaseURL, c.sources["media_base_url"])
+	}
+	if c.SMTPAddr != "smtp.example.com:587" {
+		t.Errorf("smtp_addr = %q, want the file value when the variable is unset", c.SMTPAddr)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		c := &Config{sources: map[string]string{}}
+		for _, s := range settings {
+			if err := c.set(s, s.def, SourceDefault); err != nil {
+				t.Fatal(err)
+			}
+		}
+		c.ProjectID, c.EventID, c.AdminPassword = "p", "e", "secret"
+		return c
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatalf("defaults with the required settings: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantKey string
+	}{
+		{"origin without scheme", func(c *Config) { c.CORSOrigins = []string{"example.com"} }, "cors_origins"},
+		{"origin with path", func(c *Config) { c.CORSOrigins = []string{"https://example.com/app"} }, "cors_origins"},
+		{"origin with other scheme", func(c *Config) { c.CORSOrigins = []string{"ftp://example.com"} }, "cors_origins"},
+		{"no origins", func(c *Config) { c.CORSOrigins = nil }, "cors_origins"},
+		{"negative drain", func(c *Config) { c.ShutdownDrain = -time.Second }, "shutdown_drain"},
+		{"zero retention", func(c *Config) { c.TrashRetention = 0 }, "trash_retention"},
+		{"negative cache ttl", func(c *Config) { c.AgendaCacheTTL = -time.Minute }, "agenda_cache_ttl"},
+		{"bad port", func(c *Config) { c.Port = "http" }, "port"},
+		{"relative media url", func(c *Config) { c.MediaBaseURL = "/media" }, "media_base_url"},
+		{"smtp without sender", func(c *Config) { c.SMTPAddr = "smtp.example.com:587" }, "mail_from"},
+		{"release without metrics token", func(c *Config) { c.GinMode = "release" }, "metrics_token"},
+		{"unknown gin mode", func(c *Config) { c.GinMode = "prod" }, "gin_mode"},
+		{"missing admin password", func(c *Config) { c.AdminPassword = "" }, "admin_password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.change(c)
+			err := c.Validate()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantKey+":") {
+				t.Errorf("Validate = %v, want an error for %s", err, tt.wantKey)
+			}
+		})
+	}
+
+	t.Run("origins accepted", func(t *testing.T) {
+		c := valid()
+		c.CORSOrigins = []string{"https://example.com", "http://localhost:3000", "https://example.com/"}
+		if err := c.Validate(); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	isolate(t)
+	t.Setenv("ADMIN_PASSWORD", "admin-secret-value")
+	t.Setenv("SMTP_PASSWORD", "smtp-secret-value")
+	t.Setenv("METRICS_TOKEN", "metrics-secret-value")
+	c, err := Read("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var printed bytes.Buffer
+	if err := c.Print(&printed); err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	slog.New(slog.NewTextHandler(&logged, nil)).Info("config", "config", c)
+
+	for name, out := range map[string]string{"Print": printed.String(), "LogValue": logged.String()} {
+		for _, secret := range []string{"admin-secret-value", "smtp-secret-value", "metrics-secret-value"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%s output contains %s", name, secret)
+			}
+		}
+		if n := strings.Count(out, "[REDACTED]"); n != 3 {
+			t.Errorf("%s output redacts %d values, want 3:\n%s", name, n, out)
+		}
+	}
+	found := false
+	for _, line := range strings.Split(printed.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[0] == "admin_password" {
+			found = fields[1] == "[REDACTED]" && fields[2] == SourceEnv
+		}
+	}
+	if !found {
+		t.Errorf("Print does not show the admin password redacted, from %s:\n%s", SourceEnv, printed.String())
+	}
+}
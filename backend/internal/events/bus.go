@@ -0,0 +1,130 @@
+// Package events provides an in-process publish/subscribe bus used to push
+// live updates to connected clients
+package events
+
+import "sync"
+
+// TopicRegistrations carries attendee and session changes that affect the
+// registration count and seat availability
+const TopicRegistrations = "registrations"
+
+// TopicSpeakers carries speaker changes
+const TopicSpeakers = "speakers"
+
+// subscriptionBuffer is the number of undelivered events kept per subscriber
+const subscriptionBuffer = 16
+
+// Event is a message published on a topic. Type names the event for
+// clients, e.g. the Server-Sent Events event name.
+type Event struct {
+	Topic string
+	Type  string
+	Data  interface{}
+}
+
+// Bus fans events out to the subscribers of each topic. Events are only
+// delivered within this process.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[string]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscription receives the events published on a topic until closed
+type Subscription struct {
+	bus   *Bus
+	topic string
+	ch    chan Event
+	once  sync.Once
+}
+
+// Subscribe registers a new subscriber for topic
+func (b *Bus) Subscribe(topic string) *Subscription {
+	return b.SubscribeBuffered(topic, subscriptionBuffer)
+}
+
+// SubscribeBuffered registers a subscriber that keeps up to size
+// undelivered events, for subscribers that must not miss events in bursts
+func (b *Bus) SubscribeBuffered(topic string, size int) *Subscription {
+	s := &Subscription{bus: b, topic: topic, ch: make(chan Event, size)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		s.once.Do(func() { close(s.ch) })
+		return s
+	}
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[*Subscription]struct{})
+	}
+	b.subs[topic][s] = struct{}{}
+	return s
+}
+
+// Publish delivers an event to every current subscriber of topic without
+// blocking. A subscriber that has fallen behind loses its oldest event.
+func (b *Bus) Publish(topic, eventType string, data interface{}) {
+	e := Event{Topic: topic, Type: eventType, Data: data}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs[topic] {
+		select {
+		case s.ch <- e:
+		default:
+			select {
+			case <-s.ch:
+			default:
+			}
+			select {
+			case s.ch <- e:
+			default:
+			}
+		}
+	}
+}
+
+// Close closes every subscription, which ends the streams of connected
+// clients, and makes later subscriptions start closed. It is called when
+// the server shuts down.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for topic, subs := range b.subs {
+		for s := range subs {
+			s.once.Do(func() { close(s.ch) })
+		}
+		delete(b.subs, topic)
+	}
+}
+
+// Subscribers returns the number of subscribers of topic
+func (b *Bus) Subscribers(topic string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs[topic])
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close unsubscribes. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		defer s.bus.mu.Unlock()
+		delete(s.bus.subs[s.topic], s)
+		if len(s.bus.subs[s.topic]) == 0 {
+			delete(s.bus.subs, s.topic)
+		}
+		close(s.ch)
+	})
+}
@@ -0,0 +1,15 @@
+// Package export holds helpers shared by the CSV exports of the API and
+// workshopctl
+package export
+
+import "strings"
+
+// CSVSafe stops spreadsheet applications from evaluating free text as a
+// formula, by prefixing values that start with a formula character with a
+// quote
+func CSVSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,201 @@
+// Package fixtures builds a deterministic demo event (speakers, sessions
+// and synthetic attendees) and loads it into any store. The same options
+// always produce the same IDs, names, emails and timestamps, so fixtures
+// can back local development as well as tests.
+package fixtures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store is what fixtures are loaded into. *repository.Repository
+// implements it, as does MemoryStore. Creating an entity whose ID exists
+// must return an error wrapping repository.ErrAlreadyExists and change
+// nothing.
+type Store interface {
+	CreateSpeaker(ctx context.Context, speaker *models.Speaker) error
+	CreateSession(ctx context.Context, session *models.Session) error
+	CreateAttendee(ctx context.Context, attendee *models.Attendee) error
+}
+
+// DefaultSeed is used when Options.Seed is zero
+const DefaultSeed = 2024
+
+// ReferenceDate is the event day used when Options.Date is zero
+var ReferenceDate = time.Date(2030, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+// Options control the generated data
+type Options struct {
+	// Attendees is the number of synthetic attendees
+	Attendees int
+	// Seed makes a different but still deterministic data set
+	Seed int64
+	// Date is the day the sessions take place; only its date and location
+	// are used. Attendees registered in the 30 days before it.
+	Date time.Time
+}
+
+// Set is a generated data set
+type Set struct {
+	Speakers  []models.Speaker
+	Sessions  []models.Session
+	Attendees []models.Attendee
+	// Existing is the number of entities Load skipped because the store
+	// already had them
+	Existing int
+}
+
+var speakers = []models.Speaker{
+	{Name: "Priya Raman", Bio: "Principal engineer working on cloud marketplaces and billing platforms."},
+	{Name: "Marcus Chen", Bio: "Developer advocate focused on API design and developer experience."},
+	{Name: "Elena Novak", Bio: "Product designer who has shipped design systems for SaaS products."},
+	{Name: "David Okafor", Bio: "Engineering manager leading platform and reliability teams."},
+}
+
+// sessions start at Start after midnight on the event day; Speakers are
+// indexes into speakers
+var sessions = []struct {
+	Title       string
+	Description string
+	Start       time.Duration
+	Length      time.Duration
+	Speakers    []int
+	Capacity    int
+}{
+	{"Opening Keynote", "Where marketplaces are heading and what it means for builders.", 9 * time.Hour, time.Hour, []int{0}, 200},
+	{"Designing APIs People Enjoy", "Naming, pagination, errors and versioning in practice.", 10*time.Hour + 15*time.Minute, time.Hour, []int{1}, 120},
+	{"Design Systems at Scale", "Keeping dozens of product teams visually consistent.", 13 * time.Hour, time.Hour, []int{2}, 80},
+	{"Running Platforms Without Heroics", "On-call, SLOs and incident reviews that actually change things.", 14*time.Hour + 15*time.Minute, time.Hour, []int{3}, 80},
+	{"Panel: Shipping Faster Together", "Engineering, product and design on working as one team.", 15*time.Hour + 30*time.Minute, 45 * time.Minute, []int{0, 1, 2, 3}, 200},
+}
+
+// designations are weighted roughly like a developer-focused workshop
+var designations = []struct {
+	Name   string
+	Weight int
+}{
+	{"Developer", 55},
+	{"Manager", 15},
+	{"Designer", 10},
+	{"Product Manager", 12},
+	{"Other", 8},
+}
+
+var (
+	firstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Riley", "Casey", "Jamie", "Avery", "Quinn", "Aisha", "Mateo", "Yuki", "Lena", "Omar", "Sofia"}
+	lastNames  = []string{"Smith", "Garcia", "Kim", "Patel", "Schmidt", "Rossi", "Nguyen", "Okafor", "Silva", "Cohen", "Tanaka", "Dubois", "Haddad", "Larsen"}
+)
+
+// Build generates the data set for opts without storing it
+func Build(opts Options) *Set {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = DefaultSeed
+	}
+	date := opts.Date
+	if date.IsZero() {
+		date = ReferenceDate
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	rng := rand.New(rand.NewSource(seed))
+	newID := func() string {
+		return uuid.Must(uuid.NewRandomFromReader(rng)).String()
+	}
+
+	set := &Set{
+		Speakers: make([]models.Speaker, len(speakers)),
+		Sessions: make([]models.Session, len(sessions)),
+	}
+	for i, speaker := range speakers {
+		speaker.ID = newID()
+		set.Speakers[i] = speaker
+	}
+	for i, s := range sessions {
+		startsAt := day.Add(s.Start)
+		endsAt := startsAt.Add(s.Length)
+		capacity := s.Capacity
+		session := models.Session{
+			ID:          newID(),
+			Title:       s.Title,
+			Description: s.Description,
+			Time:        startsAt.Format("3:04 PM") + " - " + endsAt.Format("3:04 PM"),
+			Capacity:    &capacity,
+			StartsAt:    &startsAt,
+			EndsAt:      &endsAt,
+		}
+		for _, j := range s.Speakers {
+			session.SpeakerIDs = append(session.SpeakerIDs, set.Speakers[j].ID)
+			set.Speakers[j].Sessions = append(set.Speakers[j].Sessions, session.ID)
+		}
+		set.Sessions[i] = session
+	}
+
+	totalWeight := 0
+	for _, d := range designations {
+		totalWeight += d.Weight
+	}
+	registrationWindow := 30 * 24 * time.Hour
+	set.Attendees = make([]models.Attendee, max(opts.Attendees, 0))
+	for i := range set.Attendees {
+		first := firstNames[rng.Intn(len(firstNames))]
+		last := lastNames[rng.Intn(len(lastNames))]
+		pick := rng.Intn(totalWeight)
+		designation := designations[0].Name
+		for _, d := range designations {
+			if pick < d.Weight {
+				designation = d.Name
+				break
+			}
+			pick -= d.Weight
+		}
+		set.Attendees[i] = models.Attendee{
+			ID:          newID(),
+			Name:        first + " " + last,
+			Email:       fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			Designation: designation,
+			// Spread evenly over the registration window, in order
+			RegisteredAt: day.Add(-registrationWindow).Add(registrationWindow * time.Duration(i) / time.Duration(opts.Attendees)),
+		}
+	}
+	return set
+}
+
+// Load builds the data set for opts and creates it in store, speakers
+// first. Entities the store already has are left as they are, so loading
+// the same options again adds nothing and emits no events.
+func Load(ctx context.Context, store Store, opts Options) (*Set, error) {
+	set := Build(opts)
+	skipExisting := func(err error) error {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			set.Existing++
+			return nil
+		}
+		return err
+	}
+	for i := range set.Speakers {
+		if err := skipExisting(store.CreateSpeaker(ctx, &set.Speakers[i])); err != nil {
+			return nil, fmt.Errorf("speaker %s: %w", set.Speakers[i].Name, err)
+		}
+	}
+	for i := range set.Sessions {
+		if err := skipExisting(store.CreateSession(ctx, &set.Sessions[i])); err != nil {
+			return nil, fmt.Errorf("session %s: %w", set.Sessions[i].Title, err)
+		}
+	}
+	for i := range set.Attendees {
+		if err := skipExisting(store.CreateAttendee(ctx, &set.Attendees[i])); err != nil {
+			return nil, fmt.Errorf("attendee %s: %w", set.Attendees[i].Email, err)
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,92 @@
+package fixtures
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildIsDeterministic(t *testing.T) {
+	opts := Options{Attendees: 40, Seed: 7, Date: time.Date(2031, time.March, 9, 15, 30, 0, 0, time.UTC)}
+	first, second := Build(opts), Build(opts)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("Build returned different data for the same options")
+	}
+
+	other := Build(Options{Attendees: 40, Seed: 8, Date: opts.Date})
+	if other.Speakers[0].ID == first.Speakers[0].ID {
+		t.Error("a different seed produced the same speaker IDs")
+	}
+}
+
+func TestBuildDefaults(t *testing.T) {
+	set := Build(Options{Attendees: 3})
+	if !reflect.DeepEqual(set, Build(Options{Attendees: 3, Seed: DefaultSeed, Date: ReferenceDate})) {
+		t.Fatal("zero options differ from DefaultSeed and ReferenceDate")
+	}
+
+	day := ReferenceDate
+	for _, session := range set.Sessions {
+		if session.StartsAt.Truncate(24*time.Hour) != day {
+			t.Errorf("session %q starts at %v, not on %v", session.Title, session.StartsAt, day)
+		}
+	}
+	for i, attendee := range set.Attendees {
+		if !attendee.RegisteredAt.Before(day) || attendee.RegisteredAt.Before(day.AddDate(0, 0, -30)) {
+			t.Errorf("attendee %d registered at %v, outside the 30 days before %v", i, attendee.RegisteredAt, day)
+		}
+		if i > 0 && attendee.RegisteredAt.Before(set.Attendees[i-1].RegisteredAt) {
+			t.Errorf("attendee %d registered before attendee %d", i, i-1)
+		}
+	}
+}
+
+func TestBuildLinksSpeakersAndSessions(t *testing.T) {
+	set := Build(Options{})
+	speakers := make(map[string]bool)
+	for _, speaker := range set.Speakers {
+		speakers[speaker.ID] = true
+	}
+	for _, session := range set.Sessions {
+		if len(session.SpeakerIDs) == 0 {
+			t.Errorf("session %q has no speakers", session.Title)
+		}
+		for _, id := range session.SpeakerIDs {
+			if !speakers[id] {
+				t.Errorf("session %q names unknown speaker %s", session.Title, id)
+			}
+		}
+	}
+}
+
+func TestLoadTwiceSkipsExisting(t *testing.T) {
+	ctx := context.Background()
+	store := &MemoryStore{}
+	opts := Options{Attendees: 10}
+
+	set, err := Load(ctx, store, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Existing != 0 {
+		t.Fatalf("first load found %d existing entities", set.Existing)
+	}
+	if len(store.Speakers) != len(set.Speakers) || len(store.Sessions) != len(set.Sessions) || len(store.Attendees) != 10 {
+		t.Fatalf("stored %d speakers, %d sessions, %d attendees", len(store.Speakers), len(store.Sessions), len(store.Attendees))
+	}
+	if !reflect.DeepEqual(store.Attendees, set.Attendees) {
+		t.Fatal("stored attendees differ from the returned set")
+	}
+
+	again, err := Load(ctx, store, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(set.Speakers) + len(set.Sessions) + len(set.Attendees); again.Existing != want {
+		t.Errorf("second load found %d existing entities, want %d", again.Existing, want)
+	}
+	if len(store.Speakers) != len(set.Speakers) || len(store.Sessions) != len(set.Sessions) || len(store.Attendees) != 10 {
+		t.Errorf("second load stored duplicates: %d speakers, %d sessions, %d attendees", len(store.Speakers), len(store.Sessions), len(store.Attendees))
+	}
+}
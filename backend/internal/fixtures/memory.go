@@ -0,0 +1,51 @@
+package fixtures
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+// MemoryStore is an in-memory Store for tests. Created entities are kept
+// in creation order.
+type MemoryStore struct {
+	mu        sync.Mutex
+	Speakers  []models.Speaker
+	Sessions  []models.Session
+	Attendees []models.Attendee
+}
+
+func (m *MemoryStore) CreateSpeaker(ctx context.Context, speaker *models.Speaker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.ContainsFunc(m.Speakers, func(s models.Speaker) bool { return s.ID == speaker.ID }) {
+		return repository.ErrAlreadyExists
+	}
+	speaker.Version = 1
+	m.Speakers = append(m.Speakers, *speaker)
+	return nil
+}
+
+func (m *MemoryStore) CreateSession(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.ContainsFunc(m.Sessions, func(s models.Session) bool { return s.ID == session.ID }) {
+		return repository.ErrAlreadyExists
+	}
+	session.Version = 1
+	m.Sessions = append(m.Sessions, *session)
+	return nil
+}
+
+func (m *MemoryStore) CreateAttendee(ctx context.Context, attendee *models.Attendee) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if slices.ContainsFunc(m.Attendees, func(a models.Attendee) bool { return a.ID == attendee.ID }) {
+		return repository.ErrAlreadyExists
+	}
+	m.Attendees = append(m.Attendees, *attendee)
+	return nil
+}
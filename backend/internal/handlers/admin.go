@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
 	"appdirect-workshop-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -21,10 +22,47 @@ func NewAdminHandler(repo *repository.Repository) *AdminHandler {
 func (h *AdminHandler) GetDesignationBreakdown(c *gin.Context) {
 	breakdown, err := h.repo.GetDesignationBreakdown(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, breakdown)
 }
 
+// GetPollResults returns the results of every poll grouped by session
+func (h *AdminHandler) GetPollResults(c *gin.Context) {
+	results, err := h.repo.GetPollResults(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// RecomputeCounters rebuilds the attendee count and designation totals
+// from the attendee records (admin only)
+func (h *AdminHandler) RecomputeCounters(c *gin.Context) {
+	count, err := h.repo.RecomputeCounters(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Counters recomputed successfully",
+		Data:    gin.H{"count": count},
+	})
+}
+
+// GetAnalyticsSnapshots returns the nightly registration snapshots, oldest
+// first (admin only)
+func (h *AdminHandler) GetAnalyticsSnapshots(c *gin.Context) {
+	snapshots, err := h.repo.GetAnalyticsSnapshots(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/live"
 	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
 	"appdirect-workshop-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -12,29 +16,46 @@ import (
 )
 
 type AttendeeHandler struct {
-	repo *repository.Repository
+	repo  *repository.Repository
+	bus   *events.Bus
+	stats *live.StatsBroadcaster
 }
 
-func NewAttendeeHandler(repo *repository.Repository) *AttendeeHandler {
-	return &AttendeeHandler{repo: repo}
+func NewAttendeeHandler(repo *repository.Repository, bus *events.Bus, stats *live.StatsBroadcaster) *AttendeeHandler {
+	return &AttendeeHandler{repo: repo, bus: bus, stats: stats}
 }
 
 // GetAttendeeCount returns the total number of registered attendees
 func (h *AttendeeHandler) GetAttendeeCount(c *gin.Context) {
 	count, err := h.repo.GetAttendeeCount(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"count": count})
 }
 
+// StreamAttendeeCount pushes the registration count and the seats left in
+// each session over Server-Sent Events whenever registrations change
+func (h *AttendeeHandler) StreamAttendeeCount(c *gin.Context) {
+	sub := h.bus.Subscribe(live.StatsTopic)
+
+	stats, err := h.stats.Snapshot(c.Request.Context())
+	if err != nil {
+		sub.Close()
+		problem.Error(c, err)
+		return
+	}
+
+	streamEvents(c, sub, &events.Event{Type: live.StatsEvent, Data: stats})
+}
+
 // RegisterAttendee creates a new attendee registration
 func (h *AttendeeHandler) RegisterAttendee(c *gin.Context) {
 	var attendee models.Attendee
 	if err := c.ShouldBindJSON(&attendee); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		problem.Bind(c, err)
 		return
 	}
 
@@ -42,9 +63,10 @@ func (h *AttendeeHandler) RegisterAttendee(c *gin.Context) {
 	attendee.RegisteredAt = time.Now()
 
 	if err := h.repo.CreateAttendee(c.Request.Context(), &attendee); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
+	h.bus.Publish(events.TopicRegistrations, models.EventAttendeeRegistered, attendee)
 
 	c.JSON(http.StatusCreated, models.SuccessResponse{
 		Message: "Registration successful",
@@ -52,15 +74,60 @@ func (h *AttendeeHandler) RegisterAttendee(c *gin.Context) {
 	})
 }
 
-// GetAllAttendees returns all attendees (admin only)
+// attendeeSortKeys are the sort keys of the attendee list
+var attendeeSortKeys = sortKeys[models.Attendee]{
+	"registeredAt": timeKey("RegisteredAt", func(a models.Attendee) *time.Time { return &a.RegisteredAt }),
+	"name":         textKey("Name", func(a models.Attendee) string { return a.Name }),
+	"email":        textKey("Email", func(a models.Attendee) string { return a.Email }),
+	"designation":  textKey("Designation", func(a models.Attendee) string { return a.Designation }),
+}
+
+// GetAllAttendees returns a page of attendees, searchable by name and
+// email and filterable by designation and registration date (admin only).
+// Without a search or designation filter, and with a date range only when
+// sorting by registration date, only the page is read.
 func (h *AttendeeHandler) GetAllAttendees(c *gin.Context) {
+	query, err := parseListQuery(c, attendeeSortKeys, "registeredAt")
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	from, to, err := timeRange(c)
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	designation := c.Query("designation")
+	id := func(a models.Attendee) string { return a.ID }
+
+	if pq, ok := pageQuery(query, attendeeSortKeys, "registeredAt", from, to); ok && designation == "" {
+		page, err := h.repo.ListAttendees(c.Request.Context(), pq)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, pageOf(page, query, attendeeSortKeys, id))
+		return
+	}
+
 	attendees, err := h.repo.GetAllAttendees(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, attendees)
+	matching := make([]models.Attendee, 0, len(attendees))
+	for _, a := range attendees {
+		if designation != "" && !strings.EqualFold(a.Designation, designation) {
+			continue
+		}
+		if !inRange(&a.RegisteredAt, from, to) || !query.matches(a.Name, a.Email) {
+			continue
+		}
+		matching = append(matching, a)
+	}
+
+	c.JSON(http.StatusOK, paginate(matching, query, attendeeSortKeys, id))
 }
 
 // GetAttendee returns a specific attendee (admin only)
@@ -68,21 +135,22 @@ func (h *AttendeeHandler) GetAttendee(c *gin.Context) {
 	id := c.Param("id")
 	attendee, err := h.repo.GetAttendee(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Attendee not found"})
+		problem.ErrorFor(c, "Attendee", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, attendee)
 }
 
-// DeleteAttendee deletes an attendee (admin only)
+// DeleteAttendee moves an attendee to the trash (admin only)
 func (h *AttendeeHandler) DeleteAttendee(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.repo.DeleteAttendee(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	err := h.repo.DeleteAttendee(c.Request.Context(), id)
+	if err != nil {
+		problem.ErrorFor(c, "Attendee", err)
 		return
 	}
+	h.bus.Publish(events.TopicRegistrations, models.EventAttendeeDeleted, id)
 
-	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Attendee deleted successfully"})
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Attendee moved to trash"})
 }
-
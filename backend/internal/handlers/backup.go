@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxArchiveBytes bounds the size of an uploaded event archive
+const maxArchiveBytes = 64 << 20
+
+type BackupHandler struct {
+	repo *repository.Repository
+}
+
+func NewBackupHandler(repo *repository.Repository) *BackupHandler {
+	return &BackupHandler{repo: repo}
+}
+
+// ExportEvent downloads the whole event as a JSON archive (admin only)
+func (h *BackupHandler) ExportEvent(c *gin.Context) {
+	archive, err := h.repo.ExportEvent(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("event-%s-%s.json", archive.EventID, archive.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.JSON(http.StatusOK, archive)
+}
+
+// RestoreEvent restores a JSON archive into this event, or the event given
+// by ?event=. ?onConflict=skip|overwrite|fail (default fail) decides what
+// happens to documents that already exist and ?dryRun=true only reports
+// what would change (admin only).
+func (h *BackupHandler) RestoreEvent(c *gin.Context) {
+	target := h.repo
+	if eventID := c.Query("event"); eventID != "" && eventID != h.repo.EventID() {
+		if !repository.ValidEventID(eventID) {
+			problem.Write(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid event ID")
+			return
+		}
+		target = h.repo.ForEvent(eventID)
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		problem.Write(c, http.StatusBadRequest, problem.CodeInvalidRequest, "dryRun must be true or false")
+		return
+	}
+	onConflict := c.DefaultQuery("onConflict", models.ConflictFail)
+
+	// Numbers are decoded as json.Number so integers stay integers
+	var archive models.EventArchive
+	dec := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&archive); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	report, err := target.RestoreEvent(c.Request.Context(), &archive, onConflict, dryRun)
+	switch {
+	case errors.Is(err, repository.ErrInvalidArchive):
+		// Archive problems read "path: message" when they concern a document
+		fields := make([]models.FieldError, len(report.Errors))
+		for i, msg := range report.Errors {
+			field, detail, ok := strings.Cut(msg, ": ")
+			if !ok {
+				field, detail = "archive", msg
+			}
+			fields[i] = models.FieldError{Field: field, Rule: "archive", Message: detail}
+		}
+		problem.Write(c, http.StatusBadRequest, problem.CodeValidation, "The archive is invalid", fields...)
+		return
+	case errors.Is(err, repository.ErrAlreadyExists):
+		problem.Write(c, http.StatusConflict, problem.CodeAlreadyExists, fmt.Sprintf(
+			"%d documents already exist; run a dry run to list them or choose onConflict=skip or overwrite", len(report.Conflicts)))
+		return
+	case err != nil:
+		problem.Error(c, err)
+		return
+	}
+
+	message := "Event restored successfully"
+	if dryRun {
+		message = "Dry run completed; nothing was written"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: message, Data: report})
+}
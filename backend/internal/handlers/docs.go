@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"appdirect-workshop-backend/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DocsHandler serves the OpenAPI document and its viewer
+type DocsHandler struct {
+	spec []byte
+}
+
+func NewDocsHandler(spec []byte) *DocsHandler {
+	return &DocsHandler{spec: spec}
+}
+
+// GetSpec returns the OpenAPI document
+func (h *DocsHandler) GetSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// GetDocs returns the HTML viewer of the OpenAPI document
+func (h *DocsHandler) GetDocs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
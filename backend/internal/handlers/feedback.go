@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/export"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FeedbackHandler struct {
+	repo *repository.Repository
+}
+
+func NewFeedbackHandler(repo *repository.Repository) *FeedbackHandler {
+	return &FeedbackHandler{repo: repo}
+}
+
+// SubmitFeedback records a registered attendee's rating of a session. The
+// workshop is single track, so every registered attendee is enrolled in
+// every session. Submissions open once the session has ended.
+func (h *FeedbackHandler) SubmitFeedback(c *gin.Context) {
+	sessionID := c.Param("id")
+	var submission models.FeedbackSubmission
+	if err := c.ShouldBindJSON(&submission); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return
+	}
+	if session.EndsAt == nil || time.Now().Before(*session.EndsAt) {
+		problem.Write(c, http.StatusForbidden, problem.CodeForbidden, "Feedback opens after the session has ended")
+		return
+	}
+
+	attendee, err := h.repo.GetAttendeeByEmail(ctx, submission.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		problem.Rejected(c)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	feedback := models.Feedback{
+		SessionID:   session.ID,
+		AttendeeID:  attendee.ID,
+		Rating:      submission.Rating,
+		Comment:     strings.TrimSpace(submission.Comment),
+		SubmittedAt: time.Now(),
+	}
+	err = h.repo.CreateFeedback(ctx, &feedback)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		problem.Write(c, http.StatusConflict, problem.CodeAlreadyExists, "Feedback already submitted for this session")
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Feedback submitted successfully",
+		Data:    feedback,
+	})
+}
+
+// GetSessionSummaries returns aggregated feedback for every session (admin only)
+func (h *FeedbackHandler) GetSessionSummaries(c *gin.Context) {
+	summaries, err := h.repo.GetSessionFeedbackSummaries(c.Request.Context(), "")
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetSessionSummary returns aggregated feedback and comments for a session (admin only)
+func (h *FeedbackHandler) GetSessionSummary(c *gin.Context) {
+	summaries, err := h.repo.GetSessionFeedbackSummaries(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if len(summaries) == 0 {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Session not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries[0])
+}
+
+// GetSpeakerSummaries returns aggregated feedback for every speaker (admin only)
+func (h *FeedbackHandler) GetSpeakerSummaries(c *gin.Context) {
+	summaries, err := h.repo.GetSpeakerFeedbackSummaries(c.Request.Context(), "")
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetSpeakerSummary returns aggregated feedback and comments for a speaker (admin only)
+func (h *FeedbackHandler) GetSpeakerSummary(c *gin.Context) {
+	summaries, err := h.repo.GetSpeakerFeedbackSummaries(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if len(summaries) == 0 {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Speaker not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries[0])
+}
+
+// ExportFeedback downloads every feedback entry as CSV, or JSON with
+// ?format=json (admin only)
+func (h *FeedbackHandler) ExportFeedback(c *gin.Context) {
+	ctx := c.Request.Context()
+	feedback, err := h.repo.GetAllFeedback(ctx)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	if c.Query("format") == "json" {
+		c.Header("Content-Disposition", `attachment; filename="feedback.json"`)
+		c.JSON(http.StatusOK, feedback)
+		return
+	}
+
+	sessions, err := h.repo.GetSessionsWithSpeakers(ctx)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	sessionMap := make(map[string]models.SessionWithSpeakers, len(sessions))
+	for _, s := range sessions {
+		sessionMap[s.ID] = s
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="feedback.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"session_id", "session_title", "speakers", "rating", "comment", "submitted_at"})
+	for _, f := range feedback {
+		session := sessionMap[f.SessionID]
+		names := make([]string, 0, len(session.Speakers))
+		for _, speaker := range session.Speakers {
+			names = append(names, speaker.Name)
+		}
+		w.Write([]string{
+			f.SessionID,
+			export.CSVSafe(session.Title),
+			export.CSVSafe(strings.Join(names, "; ")),
+			strconv.Itoa(f.Rating),
+			export.CSVSafe(f.Comment),
+			f.SubmittedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+}
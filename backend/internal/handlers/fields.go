@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"appdirect-workshop-backend/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Public session and speaker endpoints accept these query parameters:
+//
+//	fields   comma-separated JSON fields to return, with dots selecting
+//	         fields of nested objects: fields=id,title,speakers.name
+//	include  comma-separated related resources to embed: include=speakers
+//
+// Fields apply to every item of a list. Naming a nested object without
+// subfields returns all of it.
+
+// fieldSet is a parsed fields parameter. Each selected field maps to the
+// fields selected within it, or to nil when it is selected whole.
+type fieldSet map[string]fieldSet
+
+// parseFields parses the fields parameter, checking each path against the
+// JSON fields of model. It returns nil if the request selects no fields.
+func parseFields(c *gin.Context, model reflect.Type) (fieldSet, error) {
+	param, ok := c.GetQuery("fields")
+	if !ok {
+		return nil, nil
+	}
+
+	fields := fieldSet{}
+	for _, path := range strings.Split(param, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		names := strings.Split(path, ".")
+		t := model
+		for _, name := range names {
+			field, ok := jsonFields(t)[name]
+			if !ok {
+				return nil, problem.InvalidField("fields", "oneof", fmt.Sprintf("has unknown field %q", path))
+			}
+			t = field
+		}
+		fields.add(names)
+	}
+	if len(fields) == 0 {
+		return nil, problem.InvalidField("fields", "required", "must name at least one field")
+	}
+	return fields, nil
+}
+
+// add selects the field at path, keeping it whole if it already was
+func (fs fieldSet) add(path []string) {
+	sub, seen := fs[path[0]]
+	if len(path) == 1 {
+		fs[path[0]] = nil
+		return
+	}
+	if seen && sub == nil {
+		return
+	}
+	if sub == nil {
+		sub = fieldSet{}
+		fs[path[0]] = sub
+	}
+	sub.add(path[1:])
+}
+
+// String returns the selected paths in sorted order, identifying the
+// selection in entity tags
+func (fs fieldSet) String() string {
+	var paths []string
+	for name, sub := range fs {
+		if sub == nil {
+			paths = append(paths, name)
+			continue
+		}
+		for _, path := range strings.Split(sub.String(), ",") {
+			paths = append(paths, name+"."+path)
+		}
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+// project removes the fields not selected from a decoded JSON value,
+// applying to every element of arrays
+func (fs fieldSet) project(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = fs.project(v[i])
+		}
+	case map[string]interface{}:
+		for name, value := range v {
+			sub, ok := fs[name]
+			if !ok {
+				delete(v, name)
+			} else if sub != nil {
+				v[name] = sub.project(value)
+			}
+		}
+	}
+	return v
+}
+
+// jsonFields returns the JSON field names of a struct type, or of the
+// elements of a slice or pointer, with the type of each. Fields of
+// embedded structs are promoted unless shadowed, as encoding/json does.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	var promoted []map[string]reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+		case field.Anonymous && name == "":
+			promoted = append(promoted, jsonFields(field.Type))
+		case field.IsExported():
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = field.Type
+		}
+	}
+	for _, embedded := range promoted {
+		for name, typ := range embedded {
+			if _, ok := fields[name]; !ok {
+				fields[name] = typ
+			}
+		}
+	}
+	return fields
+}
+
+// parseInclude parses the include parameter, which may name the allowed
+// relations
+func parseInclude(c *gin.Context, allowed ...string) (map[string]bool, error) {
+	include := make(map[string]bool)
+	for _, name := range strings.Split(c.Query("include"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			return nil, problem.InvalidField("include", "oneof", fmt.Sprintf("can only name %s", strings.Join(allowed, ", ")))
+		}
+		include[name] = true
+	}
+	return include, nil
+}
+
+// renderFields writes body as JSON with status, reduced to the selected
+// fields if there are any
+func renderFields(c *gin.Context, status int, body interface{}, fields fieldSet) {
+	if fields == nil {
+		c.JSON(status, body)
+		return
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(status, fields.project(doc))
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"appdirect-workshop-backend/internal/buildinfo"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check reports whether a dependency can be used
+type Check func(ctx context.Context) error
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checks       map[string]Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewHealthHandler checks each named dependency on readiness probes,
+// giving all of them timeout to answer
+func NewHealthHandler(checks map[string]Check, timeout time.Duration) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: timeout}
+}
+
+// ShutDown makes readiness probes fail from now on, so load balancers stop
+// routing requests here before the server stops
+func (h *HealthHandler) ShutDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live reports that the process is up and serving requests
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Ready reports whether the server can take traffic: every dependency
+// answered in time and the server is not shutting down. It responds with
+// 503 otherwise. Why a check failed is logged, not returned, as the probe
+// is public.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	build := buildinfo.Get()
+	build.Commit = buildinfo.Short(build.Commit)
+	readiness := models.Readiness{
+		Status: models.StatusReady,
+		Checks: h.runChecks(c.Request.Context()),
+		Build:  build,
+	}
+	for _, check := range readiness.Checks {
+		if check.Status != "ok" {
+			readiness.Status = models.StatusNotReady
+		}
+	}
+	if h.shuttingDown.Load() {
+		readiness.Status = models.StatusShuttingDown
+	}
+
+	code := http.StatusOK
+	if readiness.Status != models.StatusReady {
+		code = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, readiness)
+}
+
+// runChecks runs every check concurrently within the timeout
+func (h *HealthHandler) runChecks(ctx context.Context) map[string]models.DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]models.DependencyStatus, len(h.checks))
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+			result := models.DependencyStatus{
+				Status:    "ok",
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "error"
+				logging.FromContext(ctx).Warn("Readiness check failed", "dependency", name, "error", err)
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
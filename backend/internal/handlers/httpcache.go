@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheControl returns the Cache-Control value of public cached lists:
+// shared caches may keep a response for maxAge, or must revalidate it on
+// every use when maxAge is zero
+func cacheControl(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "public, no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+}
+
+// serveCached writes body as JSON, reduced to the selected fields, with
+// its ETag, or 304 Not Modified if the client's copy, named by
+// If-None-Match, is still current. There is no Last-Modified: each
+// instance only knows when it loaded the data, not when the data changed,
+// so instances would disagree on it.
+func serveCached(c *gin.Context, tag string, maxAge time.Duration, body interface{}, fields fieldSet) {
+	tag = fieldsETag(tag, fields)
+	c.Header("ETag", tag)
+	c.Header("Cache-Control", cacheControl(maxAge))
+
+	if notModified(c.Request, tag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	renderFields(c, http.StatusOK, body, fields)
+}
+
+// variantETag derives the entity tag of a variant of a representation,
+// such as a selection of its fields, from the tag of the whole
+func variantETag(tag, variant string) string {
+	sum := sha256.Sum256([]byte(variant))
+	return strings.TrimSuffix(tag, `"`) + "-" + hex.EncodeToString(sum[:4]) + `"`
+}
+
+// fieldsETag returns the tag of the selected fields of the representation
+// tagged tag, which is tag itself when no fields are selected
+func fieldsETag(tag string, fields fieldSet) string {
+	if fields == nil {
+		return tag
+	}
+	return variantETag(tag, "fields="+fields.String())
+}
+
+// notModified evaluates If-None-Match (RFC 9110 13.1.2) against tag
+func notModified(r *http.Request, tag string) bool {
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobHandler struct {
+	repo *repository.Repository
+}
+
+func NewJobHandler(repo *repository.Repository) *JobHandler {
+	return &JobHandler{repo: repo}
+}
+
+// GetJobs returns every scheduled job with its next and last run (admin
+// only)
+func (h *JobHandler) GetJobs(c *gin.Context) {
+	jobs, err := h.repo.GetJobs(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, jobs)
+}
+
+// RunJob makes a job due now; the instance running jobs starts it within
+// seconds (admin only)
+func (h *JobHandler) RunJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	name := c.Param("name")
+	if err := h.repo.TriggerJob(ctx, name); err != nil {
+		problem.ErrorFor(c, "Job", err)
+		return
+	}
+	job, err := h.repo.GetJob(ctx, name)
+	if err != nil {
+		problem.ErrorFor(c, "Job", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message: "Job queued",
+		Data:    job,
+	})
+}
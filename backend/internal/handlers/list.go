@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin list endpoints share these query parameters and respond with a
+// models.Page:
+//
+//	limit   page size, default 50, at most 200
+//	cursor  nextCursor of the previous page
+//	sort    sort key, prefixed with "-" for descending order
+//	q       case-insensitive search
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// sortKeys maps the sort keys of a list endpoint to the stored field items
+// are ordered by
+type sortKeys[T any] map[string]sortKey[T]
+
+// sortKey orders a list by one stored field. value returns the field as a
+// string that compares the way Firestore orders the field: text as stored
+// (so case-sensitively) and times formatted by sortTime. Lists sorted in
+// memory and by Firestore therefore agree and share cursors.
+type sortKey[T any] struct {
+	field string
+	value func(T) string
+	time  bool
+}
+
+// textKey sorts by a text field
+func textKey[T any](field string, value func(T) string) sortKey[T] {
+	return sortKey[T]{field: field, value: value}
+}
+
+// timeKey sorts by a time field; unset times sort first
+func timeKey[T any](field string, value func(T) *time.Time) sortKey[T] {
+	return sortKey[T]{field: field, value: func(v T) string { return sortTime(value(v)) }, time: true}
+}
+
+// stored converts a sort value from a cursor back to the stored value
+func (k sortKey[T]) stored(value string) (interface{}, error) {
+	if !k.time {
+		return value, nil
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return time.Parse(sortTimeLayout, value)
+}
+
+// listQuery holds the pagination, sorting and search parameters of a list
+// request
+type listQuery struct {
+	limit  int
+	sort   string
+	desc   bool
+	search string
+	cursor *listCursor
+}
+
+// listCursor identifies the last item of a page by its sort value and ID,
+// so pages stay stable while items are added or removed
+type listCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"i"`
+}
+
+func parseListQuery[T any](c *gin.Context, keys sortKeys[T], defaultSort string) (*listQuery, error) {
+	q := &listQuery{
+		limit:  defaultPageSize,
+		sort:   c.DefaultQuery("sort", defaultSort),
+		search: strings.ToLower(strings.TrimSpace(c.Query("q"))),
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return nil, problem.InvalidField("limit", "range", fmt.Sprintf("must be between 1 and %d", maxPageSize))
+		}
+		q.limit = n
+	}
+
+	if strings.HasPrefix(q.sort, "-") {
+		q.sort, q.desc = q.sort[1:], true
+	}
+	if _, ok := keys[q.sort]; !ok {
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, problem.InvalidField("sort", "oneof", fmt.Sprintf("must be one of %s, optionally prefixed with -", strings.Join(names, ", ")))
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		var cursor listCursor
+		if err == nil {
+			err = json.Unmarshal(raw, &cursor)
+		}
+		if err != nil {
+			return nil, problem.InvalidField("cursor", "format", "is not a cursor returned by this endpoint")
+		}
+		if cursor.Sort != q.sort || cursor.Desc != q.desc {
+			return nil, problem.InvalidField("cursor", "sort", "belongs to a different sort order")
+		}
+		if _, err := keys[q.sort].stored(cursor.Value); err != nil {
+			return nil, problem.InvalidField("cursor", "format", "is not a cursor returned by this endpoint")
+		}
+		q.cursor = &cursor
+	}
+	return q, nil
+}
+
+// matches reports whether any of fields contains the search text, ignoring
+// case. Every item matches an empty search.
+func (q *listQuery) matches(fields ...string) bool {
+	if q.search == "" {
+		return true
+	}
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), q.search) {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate orders items by the query's sort key, with IDs breaking ties,
+// and returns the page following the cursor. Total counts every item.
+func paginate[T any](items []T, q *listQuery, keys sortKeys[T], id func(T) string) models.Page {
+	key := keys[q.sort].value
+	less := func(av, aid, bv, bid string) bool {
+		if av != bv {
+			return av < bv
+		}
+		return aid < bid
+	}
+	before := func(av, aid, bv, bid string) bool {
+		if q.desc {
+			return less(bv, bid, av, aid)
+		}
+		return less(av, aid, bv, bid)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return before(key(items[i]), id(items[i]), key(items[j]), id(items[j]))
+	})
+
+	start := 0
+	if q.cursor != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			return before(q.cursor.Value, q.cursor.ID, key(items[i]), id(items[i]))
+		})
+	}
+	end := min(start+q.limit, len(items))
+
+	page := models.Page{Items: items[start:end], Total: len(items)}
+	if end < len(items) {
+		last := items[end-1]
+		page.NextCursor = q.nextCursor(key(last), id(last))
+	}
+	return page
+}
+
+// pageQuery returns the Firestore query for the page a list request asks
+// for, or false if the list has to be filtered in memory and paginated
+// with paginate. Searches match substrings, which Firestore cannot, and a
+// time range on a field other than the sort key would need a composite
+// index; rangeKey names the sort key from and to apply to.
+func pageQuery[T any](q *listQuery, keys sortKeys[T], rangeKey string, from, to *time.Time) (repository.PageQuery, bool) {
+	if q.search != "" || ((from != nil || to != nil) && q.sort != rangeKey) {
+		return repository.PageQuery{}, false
+	}
+
+	key := keys[q.sort]
+	pq := repository.PageQuery{OrderBy: key.field, Desc: q.desc, Limit: q.limit, From: from, To: to}
+	if q.cursor != nil {
+		// Checked by parseListQuery
+		pq.AfterValue, _ = key.stored(q.cursor.Value)
+		pq.AfterID = q.cursor.ID
+	}
+	return pq, true
+}
+
+// pageOf converts a page read with a pageQuery
+func pageOf[T any](page *repository.Page[T], q *listQuery, keys sortKeys[T], id func(T) string) models.Page {
+	result := models.Page{Items: page.Items, Total: page.Total}
+	if page.More && len(page.Items) > 0 {
+		last := page.Items[len(page.Items)-1]
+		result.NextCursor = q.nextCursor(keys[q.sort].value(last), id(last))
+	}
+	return result
+}
+
+// nextCursor returns the cursor of the page after the item with the given
+// sort value and ID
+func (q *listQuery) nextCursor(value, id string) string {
+	raw, _ := json.Marshal(listCursor{Sort: q.sort, Desc: q.desc, Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// sortTimeLayout formats times so that they compare correctly as strings
+const sortTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// sortTime formats t so that times compare correctly as strings. Unset
+// times sort first.
+func sortTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(sortTimeLayout)
+}
+
+// timeRange parses the from and to query parameters as RFC 3339 times or
+// dates. from is inclusive and to is exclusive, except that a to date
+// includes the whole day.
+func timeRange(c *gin.Context) (from, to *time.Time, err error) {
+	parse := func(name string, endOfDay bool) (*time.Time, error) {
+		v := c.Query(name)
+		if v == "" {
+			return nil, nil
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return &t, nil
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return nil, problem.InvalidField(name, "format", "must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+
+	if from, err = parse("from", false); err != nil {
+		return nil, nil, err
+	}
+	if to, err = parse("to", true); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// inRange reports whether t falls within [from, to). Unset times are only
+// in an unbounded range.
+func inRange(t *time.Time, from, to *time.Time) bool {
+	if from == nil && to == nil {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	return to == nil || t.Before(*to)
+}
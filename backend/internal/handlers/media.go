@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/imaging"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for form boundaries and headers on top of the
+// photo itself when limiting the request body
+const multipartOverhead = 64 << 10
+
+// PhotoUploader validates, resizes and stores uploaded speaker photos
+type PhotoUploader struct {
+	blobs    storage.BlobStore
+	maxBytes int64
+	baseURL  string
+}
+
+// NewPhotoUploader creates an uploader. When baseURL is empty, media URLs
+// are relative, e.g. /api/media/speakers/..., and resolved by clients
+// against the API's origin.
+func NewPhotoUploader(blobs storage.BlobStore, maxBytes int64, baseURL string) *PhotoUploader {
+	return &PhotoUploader{blobs: blobs, maxBytes: maxBytes, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Upload reads the "photo" form file and stores its renditions. Renditions
+// are stored under a prefix derived from the file's hash, so their URLs can
+// be cached indefinitely. On failure the problem response is written and
+// false returned.
+func (u *PhotoUploader) Upload(c *gin.Context, speakerID string) (*models.SpeakerPhoto, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, u.maxBytes+multipartOverhead)
+	file, header, err := c.Request.FormFile("photo")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			u.tooLarge(c)
+			return nil, false
+		}
+		problem.Invalid(c, "photo", "required", "is required")
+		return nil, false
+	}
+	defer file.Close()
+
+	if header.Size > u.maxBytes {
+		u.tooLarge(c)
+		return nil, false
+	}
+	data, err := io.ReadAll(io.LimitReader(file, u.maxBytes+1))
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, false
+	}
+	if int64(len(data)) > u.maxBytes {
+		u.tooLarge(c)
+		return nil, false
+	}
+
+	if _, err := imaging.Sniff(data); err != nil {
+		problem.Write(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "The photo must be a JPEG, PNG, GIF or WebP image")
+		return nil, false
+	}
+	img, err := imaging.Decode(data)
+	if errors.Is(err, imaging.ErrImageTooLarge) {
+		problem.Write(c, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "The photo dimensions are too large")
+		return nil, false
+	}
+	if err != nil {
+		problem.Write(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "The photo could not be decoded")
+		return nil, false
+	}
+
+	sum := sha256.Sum256(data)
+	photo := &models.SpeakerPhoto{
+		KeyPrefix:  fmt.Sprintf("speakers/%s/%s/", speakerID, hex.EncodeToString(sum[:8])),
+		Renditions: make([]models.PhotoRendition, 0, len(imaging.SpeakerRenditions)),
+		UploadedAt: time.Now(),
+	}
+
+	ctx := c.Request.Context()
+	for _, r := range imaging.SpeakerRenditions {
+		resized := imaging.Resize(img, r)
+		encoded, err := imaging.EncodeRendition(resized)
+		if err != nil {
+			u.deletePrefix(ctx, photo.KeyPrefix)
+			problem.Error(c, err)
+			return nil, false
+		}
+
+		rendition := models.PhotoRendition{
+			Name:   r.Name,
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+			URLs:   make(map[string]string, len(encoded)),
+		}
+		for _, e := range encoded {
+			key := photo.KeyPrefix + r.Name + "." + e.Format.Extension()
+			if err := u.blobs.Put(ctx, key, e.Format.ContentType(), bytes.NewReader(e.Data)); err != nil {
+				u.deletePrefix(ctx, photo.KeyPrefix)
+				problem.Error(c, fmt.Errorf("failed to store photo: %w", err))
+				return nil, false
+			}
+			rendition.URLs[string(e.Format)] = u.mediaURL(key)
+		}
+		photo.Renditions = append(photo.Renditions, rendition)
+	}
+
+	return photo, true
+}
+
+// DeleteAll removes every stored photo of a speaker
+func (u *PhotoUploader) DeleteAll(ctx context.Context, speakerID string) error {
+	return u.blobs.DeletePrefix(ctx, "speakers/"+speakerID+"/")
+}
+
+func (u *PhotoUploader) deletePrefix(ctx context.Context, prefix string) {
+	if err := u.blobs.DeletePrefix(ctx, prefix); err != nil {
+		logging.FromContext(ctx).Error("Failed to delete photos", "prefix", prefix, "error", err)
+	}
+}
+
+func (u *PhotoUploader) tooLarge(c *gin.Context) {
+	problem.Write(c, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge,
+		fmt.Sprintf("The photo exceeds the maximum size of %d bytes", u.maxBytes))
+}
+
+func (u *PhotoUploader) mediaURL(key string) string {
+	return u.baseURL + "/api/media/" + key
+}
+
+// renditionURL returns the URL of a rendition in the given format, or ""
+func renditionURL(photo *models.SpeakerPhoto, name, format string) string {
+	for _, r := range photo.Renditions {
+		if r.Name == name {
+			return r.URLs[format]
+		}
+	}
+	return ""
+}
+
+type MediaHandler struct {
+	blobs storage.BlobStore
+}
+
+func NewMediaHandler(blobs storage.BlobStore) *MediaHandler {
+	return &MediaHandler{blobs: blobs}
+}
+
+// ServeMedia streams a stored blob. Keys are content addressed, so
+// responses are cacheable for a year.
+func (h *MediaHandler) ServeMedia(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	blob, err := h.blobs.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Media not found")
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	defer blob.Close()
+
+	c.DataFromReader(http.StatusOK, blob.Size, blob.ContentType, blob, map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"Last-Modified":          blob.ModTime.UTC().Format(http.TimeFormat),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"appdirect-workshop-backend/internal/problem"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// etag formats a document version as a strong entity tag
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// checkIfMatch reports whether the If-Match header matches the current
+// version, writing a 412 response if it does not. PATCH requires the
+// header, so that no patch applies to a version its client has not seen,
+// and gets a 428 response without it. PUT predates versioning, so a PUT
+// without the header is accepted as it always was.
+func checkIfMatch(c *gin.Context, version int64) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		if c.Request.Method == http.MethodPut {
+			return true
+		}
+		problem.Write(c, http.StatusPreconditionRequired, problem.CodePreconditionRequired, "Send the ETag of the version you are updating in If-Match")
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// The tag of a variant, such as a selection of fields, names the
+		// version it was derived from
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			tag = base + `"`
+		}
+		if tag == "*" || tag == etag(version) {
+			return true
+		}
+	}
+	c.Header("ETag", etag(version))
+	problem.Write(c, http.StatusPreconditionFailed, problem.CodeVersionMismatch, "The resource has changed; reload it and try again")
+	return false
+}
+
+// mergePatch applies the request body as a JSON Merge Patch (RFC 7386) to
+// current and returns the result, validated against its binding tags.
+// Fields set to null in the patch are removed. On failure it writes the
+// problem response and returns false.
+func mergePatch[T any](c *gin.Context, current *T) (*T, bool) {
+	if ct := c.GetHeader("Content-Type"); ct != "" {
+		mediaType, _, _ := mime.ParseMediaType(ct)
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			problem.Write(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+			return nil, false
+		}
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, false
+	}
+	original, err := json.Marshal(current)
+	if err != nil {
+		problem.Error(c, err)
+		return nil, false
+	}
+	merged, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		problem.Write(c, http.StatusBadRequest, problem.CodeInvalidRequest, "The body must be a JSON object")
+		return nil, false
+	}
+
+	patched := new(T)
+	if err := json.Unmarshal(merged, patched); err != nil {
+		problem.Bind(c, err)
+		return nil, false
+	}
+	if err := binding.Validator.ValidateStruct(patched); err != nil {
+		problem.Bind(c, err)
+		return nil, false
+	}
+	return patched, true
+}
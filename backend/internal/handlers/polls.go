@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// pollsEvent is the SSE event carrying a session's published polls
+const pollsEvent = "polls"
+
+type PollHandler struct {
+	repo    *repository.Repository
+	bus     *events.Bus
+	watches topicWatches
+}
+
+func NewPollHandler(repo *repository.Repository, bus *events.Bus) *PollHandler {
+	return &PollHandler{repo: repo, bus: bus}
+}
+
+func pollsTopic(sessionID string) string {
+	return "polls:" + sessionID
+}
+
+// GetPolls returns the open and closed polls of a session with results
+func (h *PollHandler) GetPolls(c *gin.Context) {
+	if !requireSession(c, h.repo) {
+		return
+	}
+	polls, err := h.repo.GetSessionPolls(c.Request.Context(), c.Param("id"), false)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, polls)
+}
+
+// StreamPolls pushes the open and closed polls of a session, with live
+// results, over Server-Sent Events whenever they change on any instance
+func (h *PollHandler) StreamPolls(c *gin.Context) {
+	if !requireSession(c, h.repo) {
+		return
+	}
+	sessionID := c.Param("id")
+	topic := pollsTopic(sessionID)
+	sub := h.bus.Subscribe(topic)
+	release := h.watches.acquire(c.Request.Context(), topic, func(ctx context.Context) error {
+		return h.repo.WatchSessionPolls(ctx, sessionID, func() { h.publish(ctx, sessionID) })
+	})
+	defer release()
+
+	polls, err := h.repo.GetSessionPolls(c.Request.Context(), sessionID, false)
+	if err != nil {
+		sub.Close()
+		problem.Error(c, err)
+		return
+	}
+
+	streamEvents(c, sub, &events.Event{Type: pollsEvent, Data: polls})
+}
+
+// Vote records a registered attendee's vote in an open poll, once per attendee
+func (h *PollHandler) Vote(c *gin.Context) {
+	var vote models.PollVote
+	if err := c.ShouldBindJSON(&vote); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	poll, ok := h.lookupPoll(c)
+	if !ok {
+		return
+	}
+	if poll.Status == models.PollDraft {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Poll not found")
+		return
+	}
+	if err := validateVote(poll, vote.OptionIDs); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	attendee, err := h.repo.GetAttendeeByEmail(ctx, vote.Email)
+	if errors.Is(err, repository.ErrNotFound) {
+		problem.Rejected(c)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	err = h.repo.VotePoll(ctx, poll.ID, attendee.ID, vote.OptionIDs)
+	switch {
+	case errors.Is(err, repository.ErrAlreadyExists):
+		problem.Write(c, http.StatusConflict, problem.CodeAlreadyExists, "You have already voted in this poll")
+		return
+	case errors.Is(err, repository.ErrPollNotOpen):
+		problem.Write(c, http.StatusConflict, problem.CodeConflict, "Poll is not open")
+		return
+	case errors.Is(err, repository.ErrNotFound):
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Poll not found")
+		return
+	case err != nil:
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Vote recorded successfully"})
+}
+
+// GetAllPolls returns every poll of a session, including drafts (admin only)
+func (h *PollHandler) GetAllPolls(c *gin.Context) {
+	polls, err := h.repo.GetSessionPolls(c.Request.Context(), c.Param("id"), true)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, polls)
+}
+
+// CreatePoll creates a draft poll for a session (admin only)
+func (h *PollHandler) CreatePoll(c *gin.Context) {
+	var input models.PollInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.repo.GetSession(ctx, c.Param("id"))
+	if err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return
+	}
+
+	poll := models.Poll{
+		ID:        uuid.New().String(),
+		SessionID: session.ID,
+		Question:  strings.TrimSpace(input.Question),
+		Options:   make([]models.PollOption, 0, len(input.Options)),
+		Multiple:  input.Multiple,
+		Status:    models.PollDraft,
+		Votes:     make(map[string]int, len(input.Options)),
+		CreatedAt: time.Now(),
+	}
+	for i, text := range input.Options {
+		id := strconv.Itoa(i + 1)
+		poll.Options = append(poll.Options, models.PollOption{ID: id, Text: strings.TrimSpace(text)})
+		poll.Votes[id] = 0
+	}
+
+	if err := h.repo.CreatePoll(ctx, &poll); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Poll created successfully",
+		Data:    poll,
+	})
+}
+
+// OpenPoll starts accepting votes (admin only)
+func (h *PollHandler) OpenPoll(c *gin.Context) {
+	h.setStatus(c, models.PollOpen)
+}
+
+// ClosePoll stops accepting votes; results remain visible (admin only)
+func (h *PollHandler) ClosePoll(c *gin.Context) {
+	h.setStatus(c, models.PollClosed)
+}
+
+// DeletePoll deletes a poll that has not been opened yet (admin only)
+func (h *PollHandler) DeletePoll(c *gin.Context) {
+	poll, ok := h.lookupPoll(c)
+	if !ok {
+		return
+	}
+	if poll.Status != models.PollDraft {
+		problem.Write(c, http.StatusConflict, problem.CodeConflict, "Only draft polls can be deleted")
+		return
+	}
+
+	if err := h.repo.DeletePoll(c.Request.Context(), poll.ID); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Poll deleted successfully"})
+}
+
+func (h *PollHandler) setStatus(c *gin.Context, status string) {
+	ctx := c.Request.Context()
+	poll, ok := h.lookupPoll(c)
+	if !ok {
+		return
+	}
+	if poll.Status == status {
+		problem.Write(c, http.StatusConflict, problem.CodeConflict, "Poll is already "+status)
+		return
+	}
+	if status == models.PollClosed && poll.Status != models.PollOpen {
+		problem.Write(c, http.StatusConflict, problem.CodeConflict, "Only open polls can be closed")
+		return
+	}
+
+	if err := h.repo.SetPollStatus(ctx, poll.ID, status); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	updated, err := h.repo.GetPoll(ctx, poll.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Poll " + status + " successfully",
+		Data:    updated,
+	})
+}
+
+// lookupPoll loads the poll in the URL and checks it belongs to the
+// session in the URL, writing an error response otherwise
+func (h *PollHandler) lookupPoll(c *gin.Context) (*models.Poll, bool) {
+	poll, err := h.repo.GetPoll(c.Request.Context(), c.Param("pollId"))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && poll.SessionID != c.Param("id")) {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Poll not found")
+		return nil, false
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return nil, false
+	}
+	return poll, true
+}
+
+// validateVote checks the chosen options against the poll and returns an
+// error describing the invalid field, or nil if the vote is valid
+func validateVote(poll *models.Poll, optionIDs []string) error {
+	if !poll.Multiple && len(optionIDs) != 1 {
+		return problem.InvalidField("optionIds", "len", "must contain exactly one option for this poll")
+	}
+
+	valid := make(map[string]bool, len(poll.Options))
+	for _, o := range poll.Options {
+		valid[o.ID] = true
+	}
+	seen := make(map[string]bool, len(optionIDs))
+	for _, id := range optionIDs {
+		if !valid[id] {
+			return problem.InvalidField("optionIds", "oneof", "contains unknown option "+strconv.Quote(id))
+		}
+		if seen[id] {
+			return problem.InvalidField("optionIds", "unique", "must not repeat options")
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// publish sends the session's published polls to stream subscribers. It is
+// called for every snapshot of the session's polls, so they are read once
+// per change, however many clients are connected.
+func (h *PollHandler) publish(ctx context.Context, sessionID string) {
+	topic := pollsTopic(sessionID)
+	if h.bus.Subscribers(topic) == 0 {
+		return
+	}
+
+	polls, err := h.repo.GetSessionPolls(ctx, sessionID, false)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to load polls", "sessionId", sessionID, "error", err)
+		return
+	}
+	h.bus.Publish(topic, pollsEvent, polls)
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// questionsEvent is the SSE event carrying a session's visible questions
+const questionsEvent = "questions"
+
+type QuestionHandler struct {
+	repo    *repository.Repository
+	bus     *events.Bus
+	watches topicWatches
+}
+
+func NewQuestionHandler(repo *repository.Repository, bus *events.Bus) *QuestionHandler {
+	return &QuestionHandler{repo: repo, bus: bus}
+}
+
+func questionsTopic(sessionID string) string {
+	return "questions:" + sessionID
+}
+
+// GetQuestions returns the visible questions of a session
+func (h *QuestionHandler) GetQuestions(c *gin.Context) {
+	if !requireSession(c, h.repo) {
+		return
+	}
+	questions, err := h.repo.GetSessionQuestions(c.Request.Context(), c.Param("id"), false)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+// StreamQuestions pushes the visible questions of a session over
+// Server-Sent Events whenever they change, on any instance, for the
+// projector view
+func (h *QuestionHandler) StreamQuestions(c *gin.Context) {
+	if !requireSession(c, h.repo) {
+		return
+	}
+	sessionID := c.Param("id")
+	topic := questionsTopic(sessionID)
+	sub := h.bus.Subscribe(topic)
+	release := h.watches.acquire(c.Request.Context(), topic, func(ctx context.Context) error {
+		return h.repo.WatchSessionQuestions(ctx, sessionID, func() { h.publish(ctx, sessionID) })
+	})
+	defer release()
+
+	questions, err := h.repo.GetSessionQuestions(c.Request.Context(), sessionID, false)
+	if err != nil {
+		sub.Close()
+		problem.Error(c, err)
+		return
+	}
+
+	streamEvents(c, sub, &events.Event{Type: questionsEvent, Data: questions})
+}
+
+// AskQuestion submits a question to a session while it is running
+func (h *QuestionHandler) AskQuestion(c *gin.Context) {
+	sessionID := c.Param("id")
+	var submission models.QuestionSubmission
+	if err := c.ShouldBindJSON(&submission); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := h.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return
+	}
+	now := time.Now()
+	if session.StartsAt != nil && now.Before(*session.StartsAt) {
+		problem.Write(c, http.StatusForbidden, problem.CodeForbidden, "Questions open when the session starts")
+		return
+	}
+	if session.EndsAt != nil && now.After(*session.EndsAt) {
+		problem.Write(c, http.StatusForbidden, problem.CodeForbidden, "Questions are closed for this session")
+		return
+	}
+
+	attendee, ok := h.lookupAttendee(c, submission.Email)
+	if !ok {
+		return
+	}
+
+	question := models.Question{
+		ID:         uuid.New().String(),
+		SessionID:  session.ID,
+		AttendeeID: attendee.ID,
+		AuthorName: strings.TrimSpace(submission.Name),
+		Text:       strings.TrimSpace(submission.Text),
+		CreatedAt:  now,
+	}
+	if err := h.repo.CreateQuestion(ctx, &question); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Question submitted successfully",
+		Data:    question,
+	})
+}
+
+// UpvoteQuestion adds an attendee's vote to a question, once per attendee
+func (h *QuestionHandler) UpvoteQuestion(c *gin.Context) {
+	var upvote models.QuestionUpvote
+	if err := c.ShouldBindJSON(&upvote); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	question, ok := h.lookupQuestion(c)
+	if !ok {
+		return
+	}
+	if question.Hidden {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Question not found")
+		return
+	}
+	attendee, ok := h.lookupAttendee(c, upvote.Email)
+	if !ok {
+		return
+	}
+
+	err := h.repo.UpvoteQuestion(ctx, question.ID, attendee.ID)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		problem.Write(c, http.StatusConflict, problem.CodeAlreadyExists, "You have already upvoted this question")
+		return
+	}
+	if err != nil {
+		problem.ErrorFor(c, "Question", err)
+		return
+	}
+
+	question.Upvotes++
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Question upvoted successfully",
+		Data:    question,
+	})
+}
+
+// GetAllQuestions returns every question of a session, including hidden
+// ones (admin only)
+func (h *QuestionHandler) GetAllQuestions(c *gin.Context) {
+	questions, err := h.repo.GetSessionQuestions(c.Request.Context(), c.Param("id"), true)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+// ModerateQuestion hides, answers or pins a question (admin only)
+func (h *QuestionHandler) ModerateQuestion(c *gin.Context) {
+	var moderation models.QuestionModeration
+	if err := c.ShouldBindJSON(&moderation); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	question, ok := h.lookupQuestion(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.ModerateQuestion(ctx, question.ID, moderation); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	updated, err := h.repo.GetQuestion(ctx, question.ID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Question updated successfully",
+		Data:    updated,
+	})
+}
+
+// lookupQuestion loads the question in the URL and checks it belongs to
+// the session in the URL, writing an error response otherwise
+func (h *QuestionHandler) lookupQuestion(c *gin.Context) (*models.Question, bool) {
+	question, err := h.repo.GetQuestion(c.Request.Context(), c.Param("questionId"))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && question.SessionID != c.Param("id")) {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Question not found")
+		return nil, false
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return nil, false
+	}
+	return question, true
+}
+
+// lookupAttendee finds the registered attendee with email, writing an
+// error response if there is none. An unknown email gets the same response
+// as any rejected request, so Q&A does not reveal who is registered.
+func (h *QuestionHandler) lookupAttendee(c *gin.Context, email string) (*models.Attendee, bool) {
+	attendee, err := h.repo.GetAttendeeByEmail(c.Request.Context(), email)
+	if errors.Is(err, repository.ErrNotFound) {
+		problem.Rejected(c)
+		return nil, false
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return nil, false
+	}
+	return attendee, true
+}
+
+// publish sends the session's current questions to stream subscribers. It
+// is called for every snapshot of the session's questions, so the list is
+// read once per change, however many clients are connected.
+func (h *QuestionHandler) publish(ctx context.Context, sessionID string) {
+	topic := questionsTopic(sessionID)
+	if h.bus.Subscribers(topic) == 0 {
+		return
+	}
+
+	questions, err := h.repo.GetSessionQuestions(ctx, sessionID, false)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to load questions", "sessionId", sessionID, "error", err)
+		return
+	}
+	h.bus.Publish(topic, questionsEvent, questions)
+}
@@ -2,8 +2,14 @@ package handlers
 
 import (
 	"net/http"
+	"reflect"
+	"slices"
+	"time"
 
+	"appdirect-workshop-backend/internal/cache"
+	"appdirect-workshop-backend/internal/events"
 	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
 	"appdirect-workshop-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -11,86 +17,276 @@ import (
 )
 
 type SessionHandler struct {
-	repo *repository.Repository
+	repo   *repository.Repository
+	bus    *events.Bus
+	agenda *cache.Agenda
 }
 
-func NewSessionHandler(repo *repository.Repository) *SessionHandler {
-	return &SessionHandler{repo: repo}
+func NewSessionHandler(repo *repository.Repository, bus *events.Bus, agenda *cache.Agenda) *SessionHandler {
+	return &SessionHandler{repo: repo, bus: bus, agenda: agenda}
 }
 
-// GetAllSessions returns all sessions with speaker details
+// GetAllSessions returns all sessions with their speakers, from the agenda
+// cache and with HTTP cache validators. The speakers are always included;
+// fields leaves them out when it selects none of theirs.
 func (h *SessionHandler) GetAllSessions(c *gin.Context) {
+	_, fields, ok := sessionQuery(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.agenda.Sessions(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	serveCached(c, sessions.ETag, h.agenda.MaxAge(), sessions.Value, fields)
+}
+
+// sessionQuery parses the include and fields parameters of the public
+// session endpoints, writing the problem response if they are invalid.
+// Selecting speakers or their fields includes the speakers.
+func sessionQuery(c *gin.Context) (map[string]bool, fieldSet, bool) {
+	include, err := parseInclude(c, "speakers")
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, nil, false
+	}
+	fields, err := parseFields(c, reflect.TypeOf(models.SessionWithSpeakers{}))
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, nil, false
+	}
+	if _, ok := fields["speakers"]; ok {
+		include["speakers"] = true
+	}
+	return include, fields, true
+}
+
+// sessionSortKeys are the sort keys of the admin session list
+var sessionSortKeys = sortKeys[models.SessionWithSpeakers]{
+	"startsAt": timeKey("StartsAt", func(s models.SessionWithSpeakers) *time.Time { return s.StartsAt }),
+	"title":    textKey("Title", func(s models.SessionWithSpeakers) string { return s.Title }),
+}
+
+// ListSessions returns a page of sessions with speaker details, searchable
+// by title, description and speaker name and filterable by speaker and
+// start time (admin only). Without a search or speaker filter, and with a
+// start time range only when sorting by start time, only the page is read.
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	query, err := parseListQuery(c, sessionSortKeys, "startsAt")
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	from, to, err := timeRange(c)
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	speakerID := c.Query("speaker")
+	id := func(s models.SessionWithSpeakers) string { return s.ID }
+
+	if pq, ok := pageQuery(query, sessionSortKeys, "startsAt", from, to); ok && speakerID == "" {
+		page, err := h.repo.ListSessionsWithSpeakers(c.Request.Context(), pq)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, pageOf(page, query, sessionSortKeys, id))
+		return
+	}
+
 	sessions, err := h.repo.GetSessionsWithSpeakers(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, sessions)
+	matching := make([]models.SessionWithSpeakers, 0, len(sessions))
+	for _, s := range sessions {
+		if speakerID != "" && !slices.Contains(s.SpeakerIDs, speakerID) {
+			continue
+		}
+		if !inRange(s.StartsAt, from, to) {
+			continue
+		}
+		fields := []string{s.Title, s.Description}
+		for _, speaker := range s.Speakers {
+			fields = append(fields, speaker.Name)
+		}
+		if !query.matches(fields...) {
+			continue
+		}
+		matching = append(matching, s)
+	}
+
+	c.JSON(http.StatusOK, paginate(matching, query, sessionSortKeys, id))
 }
 
-// GetSession returns a specific session
+// GetSession returns a specific session, with its speakers if
+// include=speakers or fields selects them
 func (h *SessionHandler) GetSession(c *gin.Context) {
+	include, fields, ok := sessionQuery(c)
+	if !ok {
+		return
+	}
+
 	id := c.Param("id")
 	session, err := h.repo.GetSession(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Session not found"})
+		problem.ErrorFor(c, "Session", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, session)
+	var body interface{} = session
+	tag := etag(session.Version)
+	if include["speakers"] {
+		speakers, err := h.agenda.Speakers(c.Request.Context())
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		body = withSpeakers(*session, speakers.Value)
+		tag = variantETag(tag, "include=speakers;"+speakers.ETag)
+	}
+
+	c.Header("ETag", fieldsETag(tag, fields))
+	renderFields(c, http.StatusOK, body, fields)
+}
+
+// withSpeakers joins a session with its speakers, in the session's order
+func withSpeakers(session models.Session, speakers []models.Speaker) models.SessionWithSpeakers {
+	joined := models.SessionWithSpeakers{Session: session, Speakers: make([]models.Speaker, 0, len(session.SpeakerIDs))}
+	for _, id := range session.SpeakerIDs {
+		if i := slices.IndexFunc(speakers, func(s models.Speaker) bool { return s.ID == id }); i >= 0 {
+			joined.Speakers = append(joined.Speakers, speakers[i])
+		}
+	}
+	return joined
 }
 
 // CreateSession creates a new session (admin only)
 func (h *SessionHandler) CreateSession(c *gin.Context) {
 	var session models.Session
 	if err := c.ShouldBindJSON(&session); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		problem.Bind(c, err)
+		return
+	}
+	if !validSchedule(c, &session) {
 		return
 	}
 
 	session.ID = uuid.New().String()
 
 	if err := h.repo.CreateSession(c.Request.Context(), &session); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
+	h.bus.Publish(events.TopicRegistrations, models.EventSessionCreated, session)
 
+	c.Header("ETag", etag(session.Version))
 	c.JSON(http.StatusCreated, models.SuccessResponse{
 		Message: "Session created successfully",
 		Data:    session,
 	})
 }
 
-// UpdateSession updates an existing session (admin only)
+// UpdateSession replaces an existing session (admin only). Clients send
+// the session's ETag in If-Match so concurrent edits are rejected rather
+// than overwritten.
 func (h *SessionHandler) UpdateSession(c *gin.Context) {
-	id := c.Param("id")
 	var session models.Session
 	if err := c.ShouldBindJSON(&session); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		problem.Bind(c, err)
 		return
 	}
 
-	if err := h.repo.UpdateSession(c.Request.Context(), id, &session); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	current, ok := h.lookupForUpdate(c)
+	if !ok {
 		return
 	}
+	h.save(c, current, &session)
+}
+
+// PatchSession applies a JSON Merge Patch to a session, leaving omitted
+// fields unchanged (admin only)
+func (h *SessionHandler) PatchSession(c *gin.Context) {
+	current, ok := h.lookupForUpdate(c)
+	if !ok {
+		return
+	}
+
+	session, ok := mergePatch(c, current)
+	if !ok {
+		return
+	}
+	h.save(c, current, session)
+}
+
+// requireSession checks that the session in the URL exists, writing an
+// error response otherwise
+func requireSession(c *gin.Context, repo *repository.Repository) bool {
+	if _, err := repo.GetSession(c.Request.Context(), c.Param("id")); err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return false
+	}
+	return true
+}
 
-	session.ID = id
+// lookupForUpdate loads the session in the URL and checks If-Match against
+// it, writing an error response otherwise
+func (h *SessionHandler) lookupForUpdate(c *gin.Context) (*models.Session, bool) {
+	session, err := h.repo.GetSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return nil, false
+	}
+	if !checkIfMatch(c, session.Version) {
+		return nil, false
+	}
+	return session, true
+}
+
+// save stores the new state of current, failing if it changed since it
+// was read
+func (h *SessionHandler) save(c *gin.Context, current, session *models.Session) {
+	if !validSchedule(c, session) {
+		return
+	}
+
+	if err := h.repo.UpdateSession(c.Request.Context(), current.ID, session, current.Version); err != nil {
+		problem.ErrorFor(c, "Session", err)
+		return
+	}
+	h.bus.Publish(events.TopicRegistrations, models.EventSessionUpdated, *session)
+
+	c.Header("ETag", etag(session.Version))
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Session updated successfully",
 		Data:    session,
 	})
 }
 
-// DeleteSession deletes a session (admin only)
+// validSchedule checks that a session ends after it starts, writing an
+// error response otherwise
+func validSchedule(c *gin.Context, session *models.Session) bool {
+	if session.StartsAt != nil && session.EndsAt != nil && !session.EndsAt.After(*session.StartsAt) {
+		problem.Invalid(c, "endsAt", "gtfield", "must be after startsAt")
+		return false
+	}
+	return true
+}
+
+// DeleteSession moves a session to the trash (admin only)
 func (h *SessionHandler) DeleteSession(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.repo.DeleteSession(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	err := h.repo.DeleteSession(c.Request.Context(), id)
+	if err != nil {
+		problem.ErrorFor(c, "Session", err)
 		return
 	}
+	h.bus.Publish(events.TopicRegistrations, models.EventSessionDeleted, id)
 
-	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Session deleted successfully"})
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Session moved to trash"})
 }
-
@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"reflect"
+	"slices"
 
+	"appdirect-workshop-backend/internal/cache"
+	"appdirect-workshop-backend/internal/events"
 	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
 	"appdirect-workshop-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -11,86 +17,348 @@ import (
 )
 
 type SpeakerHandler struct {
-	repo *repository.Repository
+	repo   *repository.Repository
+	bus    *events.Bus
+	photos *PhotoUploader
+	agenda *cache.Agenda
 }
 
-func NewSpeakerHandler(repo *repository.Repository) *SpeakerHandler {
-	return &SpeakerHandler{repo: repo}
+func NewSpeakerHandler(repo *repository.Repository, bus *events.Bus, photos *PhotoUploader, agenda *cache.Agenda) *SpeakerHandler {
+	return &SpeakerHandler{repo: repo, bus: bus, photos: photos, agenda: agenda}
 }
 
-// GetAllSpeakers returns all speakers
+// GetAllSpeakers returns all speakers, with their sessions if
+// include=sessions, from the agenda cache and with HTTP cache validators
 func (h *SpeakerHandler) GetAllSpeakers(c *gin.Context) {
-	speakers, err := h.repo.GetAllSpeakers(c.Request.Context())
+	include, fields, ok := speakerQuery(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	speakers, err := h.agenda.Speakers(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
+		return
+	}
+	if !include["sessions"] {
+		serveCached(c, speakers.ETag, h.agenda.MaxAge(), speakers.Value, fields)
 		return
 	}
 
-	c.JSON(http.StatusOK, speakers)
+	sessions, err := h.agenda.Sessions(ctx)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	body := make([]models.SpeakerWithSessions, 0, len(speakers.Value))
+	for _, speaker := range speakers.Value {
+		body = append(body, withSessions(speaker, sessions.Value))
+	}
+
+	tag := variantETag(speakers.ETag, "include=sessions;"+sessions.ETag)
+	serveCached(c, tag, h.agenda.MaxAge(), body, fields)
 }
 
-// GetSpeaker returns a specific speaker
+// speakerQuery parses the include and fields parameters of the public
+// speaker endpoints, writing the problem response if they are invalid
+func speakerQuery(c *gin.Context) (map[string]bool, fieldSet, bool) {
+	include, err := parseInclude(c, "sessions")
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, nil, false
+	}
+	model := reflect.TypeOf(models.Speaker{})
+	if include["sessions"] {
+		model = reflect.TypeOf(models.SpeakerWithSessions{})
+	}
+	fields, err := parseFields(c, model)
+	if err != nil {
+		problem.Bind(c, err)
+		return nil, nil, false
+	}
+	return include, fields, true
+}
+
+// withSessions joins a speaker with the sessions listing them, in agenda
+// order
+func withSessions(speaker models.Speaker, sessions []models.SessionWithSpeakers) models.SpeakerWithSessions {
+	joined := models.SpeakerWithSessions{Speaker: speaker, Sessions: make([]models.Session, 0)}
+	for _, session := range sessions {
+		if slices.Contains(session.SpeakerIDs, speaker.ID) {
+			joined.Sessions = append(joined.Sessions, session.Session)
+		}
+	}
+	return joined
+}
+
+// speakerSortKeys are the sort keys of the admin speaker list
+var speakerSortKeys = sortKeys[models.Speaker]{
+	"name": textKey("Name", func(s models.Speaker) string { return s.Name }),
+}
+
+// ListSpeakers returns a page of speakers, searchable by name and bio and
+// filterable by session (admin only). Without a search or session filter
+// only the page is read.
+func (h *SpeakerHandler) ListSpeakers(c *gin.Context) {
+	query, err := parseListQuery(c, speakerSortKeys, "name")
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	id := func(s models.Speaker) string { return s.ID }
+	if pq, ok := pageQuery(query, speakerSortKeys, "", nil, nil); ok && c.Query("session") == "" {
+		page, err := h.repo.ListSpeakers(ctx, pq)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, pageOf(page, query, speakerSortKeys, id))
+		return
+	}
+
+	var sessionSpeakers map[string]bool
+	if sessionID := c.Query("session"); sessionID != "" {
+		session, err := h.repo.GetSession(ctx, sessionID)
+		if errors.Is(err, repository.ErrNotFound) {
+			problem.Invalid(c, "session", "exists", "is not a known session")
+			return
+		}
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		sessionSpeakers = make(map[string]bool, len(session.SpeakerIDs))
+		for _, id := range session.SpeakerIDs {
+			sessionSpeakers[id] = true
+		}
+	}
+
+	speakers, err := h.repo.GetAllSpeakers(ctx)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	matching := make([]models.Speaker, 0, len(speakers))
+	for _, s := range speakers {
+		if sessionSpeakers != nil && !sessionSpeakers[s.ID] {
+			continue
+		}
+		if !query.matches(s.Name, s.Bio) {
+			continue
+		}
+		matching = append(matching, s)
+	}
+
+	c.JSON(http.StatusOK, paginate(matching, query, speakerSortKeys, id))
+}
+
+// GetSpeaker returns a specific speaker, with their sessions if
+// include=sessions
 func (h *SpeakerHandler) GetSpeaker(c *gin.Context) {
+	include, fields, ok := speakerQuery(c)
+	if !ok {
+		return
+	}
+
 	id := c.Param("id")
 	speaker, err := h.repo.GetSpeaker(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Speaker not found"})
+		problem.ErrorFor(c, "Speaker", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, speaker)
+	var body interface{} = speaker
+	tag := etag(speaker.Version)
+	if include["sessions"] {
+		sessions, err := h.agenda.Sessions(c.Request.Context())
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		body = withSessions(*speaker, sessions.Value)
+		tag = variantETag(tag, "include=sessions;"+sessions.ETag)
+	}
+
+	c.Header("ETag", fieldsETag(tag, fields))
+	renderFields(c, http.StatusOK, body, fields)
 }
 
 // CreateSpeaker creates a new speaker (admin only)
 func (h *SpeakerHandler) CreateSpeaker(c *gin.Context) {
 	var speaker models.Speaker
 	if err := c.ShouldBindJSON(&speaker); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		problem.Bind(c, err)
 		return
 	}
 
 	speaker.ID = uuid.New().String()
+	speaker.Photo = nil
 
 	if err := h.repo.CreateSpeaker(c.Request.Context(), &speaker); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+		problem.Error(c, err)
 		return
 	}
+	h.bus.Publish(events.TopicSpeakers, models.EventSpeakerCreated, speaker)
 
+	c.Header("ETag", etag(speaker.Version))
 	c.JSON(http.StatusCreated, models.SuccessResponse{
 		Message: "Speaker created successfully",
 		Data:    speaker,
 	})
 }
 
-// UpdateSpeaker updates an existing speaker (admin only)
+// UpdateSpeaker replaces an existing speaker (admin only). Clients send
+// the speaker's ETag in If-Match so concurrent edits are rejected rather
+// than overwritten.
 func (h *SpeakerHandler) UpdateSpeaker(c *gin.Context) {
-	id := c.Param("id")
 	var speaker models.Speaker
 	if err := c.ShouldBindJSON(&speaker); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		problem.Bind(c, err)
+		return
+	}
+
+	current, ok := h.lookupForUpdate(c)
+	if !ok {
+		return
+	}
+	h.save(c, current, &speaker)
+}
+
+// PatchSpeaker applies a JSON Merge Patch to a speaker, leaving omitted
+// fields unchanged (admin only)
+func (h *SpeakerHandler) PatchSpeaker(c *gin.Context) {
+	current, ok := h.lookupForUpdate(c)
+	if !ok {
 		return
 	}
 
-	if err := h.repo.UpdateSpeaker(c.Request.Context(), id, &speaker); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	speaker, ok := mergePatch(c, current)
+	if !ok {
 		return
 	}
+	h.save(c, current, speaker)
+}
 
-	speaker.ID = id
+// lookupForUpdate loads the speaker in the URL and checks If-Match against
+// it, writing an error response otherwise
+func (h *SpeakerHandler) lookupForUpdate(c *gin.Context) (*models.Speaker, bool) {
+	speaker, err := h.repo.GetSpeaker(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.ErrorFor(c, "Speaker", err)
+		return nil, false
+	}
+	if !checkIfMatch(c, speaker.Version) {
+		return nil, false
+	}
+	return speaker, true
+}
+
+// save stores the new state of current, failing if it changed since it
+// was read
+func (h *SpeakerHandler) save(c *gin.Context, current, speaker *models.Speaker) {
+	// The photo is managed by the upload endpoint, so keep the stored one
+	speaker.Photo = current.Photo
+	if speaker.PhotoURL == "" && current.Photo != nil {
+		speaker.PhotoURL = current.PhotoURL
+	}
+
+	if err := h.repo.UpdateSpeaker(c.Request.Context(), current.ID, speaker, current.Version); err != nil {
+		problem.ErrorFor(c, "Speaker", err)
+		return
+	}
+	h.bus.Publish(events.TopicSpeakers, models.EventSpeakerUpdated, *speaker)
+
+	c.Header("ETag", etag(speaker.Version))
 	c.JSON(http.StatusOK, models.SuccessResponse{
 		Message: "Speaker updated successfully",
 		Data:    speaker,
 	})
 }
 
-// DeleteSpeaker deletes a speaker (admin only)
+// DeleteSpeaker moves a speaker to the trash (admin only). Its photos are
+// kept until the speaker is purged.
 func (h *SpeakerHandler) DeleteSpeaker(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.repo.DeleteSpeaker(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	err := h.repo.DeleteSpeaker(c.Request.Context(), id)
+	if err != nil {
+		problem.ErrorFor(c, "Speaker", err)
 		return
 	}
+	h.bus.Publish(events.TopicSpeakers, models.EventSpeakerDeleted, id)
 
-	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Speaker deleted successfully"})
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Speaker moved to trash"})
 }
 
+// UploadSpeakerPhoto stores a new photo for a speaker (admin only)
+func (h *SpeakerHandler) UploadSpeakerPhoto(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.repo.GetSpeaker(c.Request.Context(), id); err != nil {
+		problem.ErrorFor(c, "Speaker", err)
+		return
+	}
+
+	photo, ok := h.photos.Upload(c, id)
+	if !ok {
+		return
+	}
+
+	photoURL := renditionURL(photo, "card", "jpeg")
+	speaker, previous, err := h.repo.SetSpeakerPhoto(c.Request.Context(), id, photo, func(*models.Speaker) string {
+		return photoURL
+	})
+	if err != nil {
+		h.photos.deletePrefix(c.Request.Context(), photo.KeyPrefix)
+		problem.ErrorFor(c, "Speaker", err)
+		return
+	}
+
+	// Remove the renditions of the replaced photo
+	if previous != nil && previous.KeyPrefix != photo.KeyPrefix {
+		h.photos.deletePrefix(c.Request.Context(), previous.KeyPrefix)
+	}
+
+	h.bus.Publish(events.TopicSpeakers, models.EventSpeakerUpdated, *speaker)
+	c.Header("ETag", etag(speaker.Version))
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Speaker photo uploaded successfully",
+		Data:    speaker,
+	})
+}
+
+// DeleteSpeakerPhoto removes a speaker's uploaded photo (admin only)
+func (h *SpeakerHandler) DeleteSpeakerPhoto(c *gin.Context) {
+	id := c.Param("id")
+	current, err := h.repo.GetSpeaker(c.Request.Context(), id)
+	if err != nil {
+		problem.ErrorFor(c, "Speaker", err)
+		return
+	}
+	if current.Photo == nil {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Speaker has no uploaded photo")
+		return
+	}
+
+	speaker, previous, err := h.repo.SetSpeakerPhoto(c.Request.Context(), id, nil, func(current *models.Speaker) string {
+		// Keep a PhotoURL that was set by hand rather than by the upload
+		if current.Photo != nil && current.PhotoURL == renditionURL(current.Photo, "card", "jpeg") {
+			return ""
+		}
+		return current.PhotoURL
+	})
+	if err != nil {
+		problem.ErrorFor(c, "Speaker", err)
+		return
+	}
+	if previous != nil {
+		h.photos.deletePrefix(c.Request.Context(), previous.KeyPrefix)
+	}
+
+	h.bus.Publish(events.TopicSpeakers, models.EventSpeakerUpdated, *speaker)
+
+	c.Header("ETag", etag(speaker.Version))
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Speaker photo deleted successfully"})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sseHeartbeat keeps idle streams open through proxies and load balancers
+const sseHeartbeat = 15 * time.Second
+
+// streamEvents writes the events of sub to the client as Server-Sent Events
+// until the client disconnects or sub is closed, e.g. by the bus closing on
+// shutdown. The initial event, if set, is sent first so
+// clients start from a complete snapshot.
+func streamEvents(c *gin.Context, sub *events.Subscription, initial *events.Event) {
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if initial != nil {
+		c.SSEvent(initial.Type, initial.Data)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			c.SSEvent(e.Type, e.Data)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// watchRetry is how long a failed Firestore listener waits before it is
+// started again
+const watchRetry = 5 * time.Second
+
+// topicWatches runs one Firestore listener per bus topic while the topic has
+// stream subscribers on this instance, so that changes made on any instance
+// reach them. The zero value is ready to use.
+type topicWatches struct {
+	mu      sync.Mutex
+	running map[string]*topicWatch
+}
+
+type topicWatch struct {
+	streams int
+	cancel  context.CancelFunc
+}
+
+// acquire starts watch for topic unless it is already running, and returns
+// the function that stops it once the last stream using it has ended. watch
+// is restarted after errors until then. It runs with the values of ctx, such
+// as the logger, but not its cancellation.
+func (w *topicWatches) acquire(ctx context.Context, topic string, watch func(ctx context.Context) error) (release func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running == nil {
+		w.running = make(map[string]*topicWatch)
+	}
+	tw := w.running[topic]
+	if tw == nil {
+		watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		tw = &topicWatch{cancel: cancel}
+		w.running[topic] = tw
+		go keepWatching(watchCtx, topic, watch)
+	}
+	tw.streams++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if tw.streams--; tw.streams == 0 {
+				tw.cancel()
+				delete(w.running, topic)
+			}
+		})
+	}
+}
+
+func keepWatching(ctx context.Context, topic string, watch func(ctx context.Context) error) {
+	for {
+		err := watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).Error("Firestore listener failed", "topic", topic, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetry):
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler serves the trash of soft-deleted attendees, speakers and
+// sessions, which are purged permanently after the retention period
+type TrashHandler struct {
+	repo      *repository.Repository
+	bus       *events.Bus
+	photos    *PhotoUploader
+	retention time.Duration
+}
+
+func NewTrashHandler(repo *repository.Repository, bus *events.Bus, photos *PhotoUploader, retention time.Duration) *TrashHandler {
+	return &TrashHandler{repo: repo, bus: bus, photos: photos, retention: retention}
+}
+
+// trashKinds are the values of the :kind URL parameter
+var trashKinds = map[string]bool{
+	repository.KindAttendees: true,
+	repository.KindSpeakers:  true,
+	repository.KindSessions:  true,
+}
+
+// GetTrash returns the trashed attendees, speakers or sessions (admin only)
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	kind, ok := trashKind(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.repo.GetTrash(c.Request.Context(), kind)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(h.retention)
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// RestoreFromTrash moves a trashed entity back (admin only)
+func (h *TrashHandler) RestoreFromTrash(c *gin.Context) {
+	kind, ok := trashKind(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.repo.RestoreFromTrash(c.Request.Context(), kind, c.Param("id"))
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Not found in trash")
+		return
+	case errors.Is(err, repository.ErrAlreadyExists):
+		problem.Write(c, http.StatusConflict, problem.CodeAlreadyExists, "An item with this ID already exists")
+		return
+	case err != nil:
+		problem.Error(c, err)
+		return
+	}
+	h.publishRestored(kind, item.Item)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Restored successfully",
+		Data:    item.Item,
+	})
+}
+
+// PurgeFromTrash permanently deletes a trashed entity (admin only)
+func (h *TrashHandler) PurgeFromTrash(c *gin.Context) {
+	kind, ok := trashKind(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	item, err := h.repo.DeleteFromTrash(ctx, kind, c.Param("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Not found in trash")
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	h.cleanUp(ctx, item)
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Deleted permanently"})
+}
+
+// PurgeExpired permanently deletes everything trashed longer than the
+// retention period ago
+func (h *TrashHandler) PurgeExpired(ctx context.Context) error {
+	purged, err := h.repo.PurgeTrash(ctx, time.Now().Add(-h.retention))
+	for i := range purged {
+		h.cleanUp(ctx, &purged[i])
+	}
+	if len(purged) > 0 {
+		logging.FromContext(ctx).Info("Purged items from the trash", "count", len(purged))
+	}
+	return err
+}
+
+// publishRestored tells live subscribers about a restored entity with the
+// event its creation publishes, on the same topic
+func (h *TrashHandler) publishRestored(kind string, item interface{}) {
+	switch kind {
+	case repository.KindAttendees:
+		h.bus.Publish(events.TopicRegistrations, models.EventAttendeeRegistered, item)
+	case repository.KindSessions:
+		h.bus.Publish(events.TopicRegistrations, models.EventSessionCreated, item)
+	case repository.KindSpeakers:
+		h.bus.Publish(events.TopicSpeakers, models.EventSpeakerCreated, item)
+	}
+}
+
+// cleanUp removes the data kept outside Firestore for a purged entity
+func (h *TrashHandler) cleanUp(ctx context.Context, item *models.TrashItem) {
+	if item.Kind != repository.KindSpeakers {
+		return
+	}
+	if err := h.photos.DeleteAll(ctx, item.ID); err != nil {
+		logging.FromContext(ctx).Error("Failed to delete speaker photos", "speakerId", item.ID, "error", err)
+	}
+}
+
+// trashKind returns the kind in the URL, writing an error response if it
+// is unknown
+func trashKind(c *gin.Context) (string, bool) {
+	kind := c.Param("kind")
+	if !trashKinds[kind] {
+		problem.Write(c, http.StatusNotFound, problem.CodeNotFound, "Unknown trash "+kind)
+		return "", false
+	}
+	return kind, true
+}
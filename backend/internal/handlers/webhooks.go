@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+	"appdirect-workshop-backend/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	repo       *repository.Repository
+	dispatcher *webhooks.Dispatcher
+}
+
+func NewWebhookHandler(repo *repository.Repository, dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{repo: repo, dispatcher: dispatcher}
+}
+
+// GetWebhooks returns every webhook without its secret (admin only)
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	list, err := h.repo.GetWebhooks(c.Request.Context())
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	for i := range list {
+		list[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// GetWebhook returns a webhook without its secret (admin only)
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	webhook, err := h.repo.GetWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	webhook.Secret = ""
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// CreateWebhook subscribes a URL to events. The response includes the
+// signing secret, which is not shown again (admin only).
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	if err := webhooks.CheckURL(input.URL); err != nil {
+		problem.Invalid(c, "url", "https_url", err.Error())
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	webhook := models.Webhook{
+		ID:          uuid.New().String(),
+		URL:         input.URL,
+		Events:      input.Events,
+		Description: input.Description,
+		Secret:      secret,
+		Active:      true,
+		CreatedAt:   time.Now(),
+	}
+	if err := h.repo.CreateWebhook(c.Request.Context(), &webhook); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Message: "Webhook created; store the secret, it is not shown again",
+		Data:    webhook,
+	})
+}
+
+// UpdateWebhook changes the URL, events and description of a webhook
+// (admin only)
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	var input models.WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	if err := webhooks.CheckURL(input.URL); err != nil {
+		problem.Invalid(c, "url", "https_url", err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if err := h.repo.UpdateWebhook(ctx, id, &input); err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	webhook, err := h.repo.GetWebhook(ctx, id)
+	if err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	webhook.Secret = ""
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: "Webhook updated successfully",
+		Data:    webhook,
+	})
+}
+
+// EnableWebhook resumes deliveries to a webhook and clears its failure
+// count (admin only)
+func (h *WebhookHandler) EnableWebhook(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+// DisableWebhook stops deliveries to a webhook (admin only)
+func (h *WebhookHandler) DisableWebhook(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *WebhookHandler) setActive(c *gin.Context, active bool) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	if err := h.repo.SetWebhookActive(ctx, id, active, "Disabled by an admin"); err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	webhook, err := h.repo.GetWebhook(ctx, id)
+	if err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	webhook.Secret = ""
+
+	message := "Webhook disabled"
+	if active {
+		message = "Webhook enabled"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: message, Data: webhook})
+}
+
+// DeleteWebhook removes a webhook; its delivery log is kept (admin only)
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.repo.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Webhook deleted successfully"})
+}
+
+// deliverySortKeys are the sort keys of the delivery log
+var deliverySortKeys = sortKeys[models.WebhookDelivery]{
+	"createdAt": timeKey("CreatedAt", func(d models.WebhookDelivery) *time.Time { return &d.CreatedAt }),
+}
+
+// GetDeliveries returns a page of a webhook's delivery log, newest first
+// by default and filterable by status and event (admin only)
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	query, err := parseListQuery(c, deliverySortKeys, "-createdAt")
+	if err != nil {
+		problem.Bind(c, err)
+		return
+	}
+	status, event := c.Query("status"), c.Query("event")
+
+	deliveries, err := h.repo.GetWebhookDeliveries(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	matching := make([]models.WebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		if (status != "" && d.Status != status) || (event != "" && d.Event != event) {
+			continue
+		}
+		if !query.matches(d.Event, d.Error) {
+			continue
+		}
+		matching = append(matching, d)
+	}
+
+	c.JSON(http.StatusOK, paginate(matching, query, deliverySortKeys, func(d models.WebhookDelivery) string { return d.ID }))
+}
+
+// ReplayDelivery sends the event of a logged delivery again, as a new
+// delivery with the same event ID (admin only)
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	ctx := c.Request.Context()
+	delivery, err := h.repo.GetWebhookDelivery(ctx, c.Param("deliveryId"))
+	if err == nil && delivery.WebhookID != c.Param("id") {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		problem.ErrorFor(c, "Delivery", err)
+		return
+	}
+
+	webhook, err := h.repo.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		problem.ErrorFor(c, "Webhook", err)
+		return
+	}
+	if !webhook.Active {
+		problem.Write(c, http.StatusConflict, problem.CodeConflict, "Enable the webhook before replaying deliveries")
+		return
+	}
+
+	replay, err := h.dispatcher.Replay(ctx, delivery)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Message: "Delivery queued",
+		Data:    replay,
+	})
+}
@@ -0,0 +1,182 @@
+// Package imaging validates uploaded images and produces resized renditions
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/http"
+
+	// Register decoders for the accepted upload formats
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrImageTooLarge     = errors.New("image dimensions are too large")
+)
+
+// MaxPixels bounds the decoded size of an upload to guard against
+// decompression bombs
+const MaxPixels = 40_000_000
+
+// jpegQuality is used for all JPEG renditions
+const jpegQuality = 85
+
+// Format is an output encoding for a rendition
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	WebP Format = "webp"
+)
+
+// Extension returns the file extension used when storing the format
+func (f Format) Extension() string {
+	if f == JPEG {
+		return "jpg"
+	}
+	return string(f)
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Rendition describes one resized version of an uploaded image
+type Rendition struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop fills the exact Width x Height box, cropping the centre of the
+	// image. Otherwise the image is scaled to fit inside the box.
+	Crop bool
+}
+
+// SpeakerRenditions are generated for every uploaded speaker photo
+var SpeakerRenditions = []Rendition{
+	{Name: "thumbnail", Width: 96, Height: 96, Crop: true},
+	{Name: "card", Width: 320, Height: 320, Crop: true},
+	{Name: "full", Width: 1200, Height: 1200},
+}
+
+// Encoded is an image encoded in a single format
+type Encoded struct {
+	Format Format
+	Data   []byte
+}
+
+// allowedTypes are the sniffed content types accepted for upload
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Sniff detects the content type of data from its leading bytes and
+// returns ErrUnsupportedFormat unless it is an accepted image type
+func Sniff(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, contentType)
+	}
+	return contentType, nil
+}
+
+// Decode validates the dimensions of data before decoding it
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	return img, nil
+}
+
+// Resize scales img for the rendition. Images are never upscaled.
+func Resize(img image.Image, r Rendition) image.Image {
+	src := img.Bounds()
+	srcW, srcH := src.Dx(), src.Dy()
+
+	var dstW, dstH int
+	if r.Crop {
+		// Crop the largest centred region with the rendition's aspect ratio
+		cropW, cropH := srcW, srcW*r.Height/r.Width
+		if cropH > srcH {
+			cropW, cropH = srcH*r.Width/r.Height, srcH
+		}
+		x0 := src.Min.X + (srcW-cropW)/2
+		y0 := src.Min.Y + (srcH-cropH)/2
+		src = image.Rect(x0, y0, x0+cropW, y0+cropH)
+		dstW, dstH = min(r.Width, cropW), min(r.Height, cropH)
+	} else {
+		dstW, dstH = srcW, srcH
+		if dstW > r.Width {
+			dstW, dstH = r.Width, max(1, dstH*r.Width/dstW)
+		}
+		if dstH > r.Height {
+			dstW, dstH = max(1, dstW*r.Height/dstH), r.Height
+		}
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+// Encode writes img to w in the given format
+func Encode(w io.Writer, img image.Image, f Format) error {
+	switch f {
+	case JPEG:
+		return jpeg.Encode(w, flatten(img), &jpeg.Options{Quality: jpegQuality})
+	case WebP:
+		return EncodeWebP(w, img)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedFormat, f)
+	}
+}
+
+// EncodeRendition encodes img as JPEG and as lossless WebP. Lossless WebP
+// is often larger than JPEG for photographs; clients pick the format from
+// the rendition's URLs.
+func EncodeRendition(img image.Image) ([]Encoded, error) {
+	var jpg, webp bytes.Buffer
+	if err := Encode(&jpg, img, JPEG); err != nil {
+		return nil, err
+	}
+	if err := Encode(&webp, img, WebP); err != nil {
+		return nil, err
+	}
+
+	return []Encoded{
+		{Format: JPEG, Data: jpg.Bytes()},
+		{Format: WebP, Data: webp.Bytes()},
+	}, nil
+}
+
+// flatten composites img onto a white background, since JPEG has no alpha
+// channel and transparent pixels would otherwise turn black
+func flatten(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
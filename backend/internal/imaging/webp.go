@@ -0,0 +1,443 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"sort"
+)
+
+// VP8L (lossless WebP) bitstream constants
+const (
+	vp8lSignature = 0x2f
+	vp8lMaxSize   = 1 << 14
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	// predictorGradient is predictor mode 12, ClampAddSubtractFull(L, T, TL)
+	predictorGradient = 12
+	// predictorBits is the log2 block size of the predictor sub-image. The
+	// whole image uses a single mode, so the largest block keeps it tiny.
+	predictorBits = 9
+
+	numLiteralCodes  = 256
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	numCodeLengths   = 19
+
+	maxCodeLength       = 15
+	maxCodeLengthLength = 7
+)
+
+var codeLengthOrder = [numCodeLengths]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// EncodeWebP writes img as a lossless WebP (VP8L) image.
+//
+// The encoder applies the subtract-green and gradient predictor transforms
+// and entropy codes the residuals with a single set of prefix codes. It does
+// not search for backward references, which keeps it simple at some cost in
+// compression.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width <= 0 || height <= 0 || width > vp8lMaxSize || height > vp8lMaxSize {
+		return errors.New("webp: invalid image dimensions")
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+
+	argb := make([]uint32, width*height)
+	hasAlpha := false
+	for i := range argb {
+		p := nrgba.Pix[i*4 : i*4+4]
+		r, g, bl, a := uint32(p[0]), uint32(p[1]), uint32(p[2]), uint32(p[3])
+		if a != 0xff {
+			hasAlpha = true
+		}
+		// Subtract-green transform
+		r = (r - g) & 0xff
+		bl = (bl - g) & 0xff
+		argb[i] = a<<24 | r<<16 | g<<8 | bl
+	}
+	residuals := predictGradient(argb, width, height)
+
+	bw := &bitWriter{}
+	bw.writeBits(vp8lSignature, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // version
+
+	// Transforms are listed in the order they were applied
+	bw.writeBits(1, 1)
+	bw.writeBits(transformSubtractGreen, 2)
+
+	bw.writeBits(1, 1)
+	bw.writeBits(transformPredictor, 2)
+	bw.writeBits(predictorBits-2, 3)
+	writePredictorImage(bw)
+
+	bw.writeBits(0, 1) // no more transforms
+
+	bw.writeBits(0, 1) // no color cache
+	bw.writeBits(0, 1) // no meta prefix codes
+	writeEntropyCodedImage(bw, residuals)
+
+	data := bw.bytes()
+	return writeRIFF(w, data)
+}
+
+// predictGradient returns the per-channel residuals of argb against the
+// VP8L predictor, using the gradient mode away from the image edges.
+func predictGradient(argb []uint32, width, height int) []uint32 {
+	out := make([]uint32, len(argb))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			var pred uint32
+			switch {
+			case x == 0 && y == 0:
+				pred = 0xff000000
+			case y == 0:
+				pred = argb[i-1]
+			case x == 0:
+				pred = argb[i-width]
+			default:
+				pred = clampAddSubtractFull(argb[i-1], argb[i-width], argb[i-width-1])
+			}
+			out[i] = subPixels(argb[i], pred)
+		}
+	}
+	return out
+}
+
+func clampAddSubtractFull(l, t, tl uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		v := int(l>>shift&0xff) + int(t>>shift&0xff) - int(tl>>shift&0xff)
+		if v < 0 {
+			v = 0
+		} else if v > 0xff {
+			v = 0xff
+		}
+		out |= uint32(v) << shift
+	}
+	return out
+}
+
+func subPixels(a, b uint32) uint32 {
+	var out uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		out |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return out
+}
+
+// writePredictorImage writes the predictor sub-image, which selects the
+// gradient mode for every block. Every prefix code has a single symbol, so
+// the pixels themselves take no bits.
+func writePredictorImage(bw *bitWriter) {
+	bw.writeBits(0, 1) // no color cache
+	writeSimpleCode(bw, predictorGradient)
+	writeSimpleCode(bw, 0)
+	writeSimpleCode(bw, 0)
+	writeSimpleCode(bw, 0)
+	writeSimpleCode(bw, 0)
+}
+
+// writeEntropyCodedImage writes the prefix codes for the pixels followed by
+// the pixels as literals.
+func writeEntropyCodedImage(bw *bitWriter, pixels []uint32) {
+	green := make([]int, numLiteralCodes+numLengthCodes)
+	red := make([]int, numLiteralCodes)
+	blue := make([]int, numLiteralCodes)
+	alpha := make([]int, numLiteralCodes)
+	for _, p := range pixels {
+		alpha[p>>24]++
+		red[p>>16&0xff]++
+		green[p>>8&0xff]++
+		blue[p&0xff]++
+	}
+
+	codes := [4]*prefixCode{
+		writePrefixCode(bw, green),
+		writePrefixCode(bw, red),
+		writePrefixCode(bw, blue),
+		writePrefixCode(bw, alpha),
+	}
+	writeSimpleCode(bw, 0) // distance codes are unused
+
+	for _, p := range pixels {
+		codes[0].write(bw, int(p>>8&0xff))
+		codes[1].write(bw, int(p>>16&0xff))
+		codes[2].write(bw, int(p&0xff))
+		codes[3].write(bw, int(p>>24))
+	}
+}
+
+func writeRIFF(w io.Writer, data []byte) error {
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+8+len(data)+pad))
+	copy(header[8:12], "WEBP")
+	copy(header[12:16], "VP8L")
+	binary.LittleEndian.PutUint32(header[16:20], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// prefixCode holds the bit-reversed canonical codes for an alphabet
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (p *prefixCode) write(bw *bitWriter, symbol int) {
+	if n := p.lengths[symbol]; n > 0 {
+		bw.writeBits(p.codes[symbol], uint(n))
+	}
+}
+
+// writeSimpleCode writes a prefix code with a single symbol, which
+// encodes to zero bits.
+func writeSimpleCode(bw *bitWriter, symbol int) {
+	bw.writeBits(1, 1) // simple code
+	bw.writeBits(0, 1) // one symbol
+	if symbol < 2 {
+		bw.writeBits(0, 1)
+		bw.writeBits(uint32(symbol), 1)
+	} else {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(symbol), 8)
+	}
+}
+
+// writePrefixCode writes the prefix code for the histogram and returns it
+func writePrefixCode(bw *bitWriter, histogram []int) *prefixCode {
+	used, last := 0, 0
+	for s, n := range histogram {
+		if n > 0 {
+			used++
+			last = s
+		}
+	}
+	if used <= 1 && last < numLiteralCodes {
+		writeSimpleCode(bw, last)
+		return &prefixCode{lengths: make([]uint8, len(histogram)), codes: make([]uint32, len(histogram))}
+	}
+
+	lengths := huffmanLengths(histogram, maxCodeLength)
+
+	// Encode the code lengths, using run-length codes for zeros
+	var tokens, extra []int
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens, extra = append(tokens, int(lengths[i])), append(extra, 0)
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == 0 && run < 138 {
+			run++
+		}
+		switch {
+		case run >= 11:
+			tokens, extra = append(tokens, 18), append(extra, run-11)
+		case run >= 3:
+			tokens, extra = append(tokens, 17), append(extra, run-3)
+		default:
+			run = 1
+			tokens, extra = append(tokens, 0), append(extra, 0)
+		}
+		i += run
+	}
+
+	clHistogram := make([]int, numCodeLengths)
+	for _, t := range tokens {
+		clHistogram[t]++
+	}
+	clLengths := huffmanLengths(clHistogram, maxCodeLengthLength)
+	clCode := newPrefixCode(clLengths)
+
+	numCodes := 4
+	for i, s := range codeLengthOrder {
+		if clLengths[s] != 0 && i+1 > numCodes {
+			numCodes = i + 1
+		}
+	}
+
+	bw.writeBits(0, 1) // normal code
+	bw.writeBits(uint32(numCodes-4), 4)
+	for _, s := range codeLengthOrder[:numCodes] {
+		bw.writeBits(uint32(clLengths[s]), 3)
+	}
+	bw.writeBits(0, 1) // code lengths cover the whole alphabet
+	for i, t := range tokens {
+		clCode.write(bw, t)
+		switch t {
+		case 17:
+			bw.writeBits(uint32(extra[i]), 3)
+		case 18:
+			bw.writeBits(uint32(extra[i]), 7)
+		}
+	}
+
+	return newPrefixCode(lengths)
+}
+
+// newPrefixCode assigns canonical codes to the given code lengths
+func newPrefixCode(lengths []uint8) *prefixCode {
+	var count [maxCodeLength + 1]uint32
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		c := next[l]
+		next[l]++
+		// Codes are stored most significant bit first in an LSB-first stream
+		var rev uint32
+		for i := uint8(0); i < l; i++ {
+			rev = rev<<1 | c>>i&1
+		}
+		codes[s] = rev
+	}
+	return &prefixCode{lengths: lengths, codes: codes}
+}
+
+// huffmanLengths computes Huffman code lengths limited to maxLength. At
+// least two symbols always get a code so the result is a complete code.
+func huffmanLengths(histogram []int, maxLength int) []uint8 {
+	counts := make([]int, len(histogram))
+	copy(counts, histogram)
+
+	used := 0
+	for _, n := range counts {
+		if n > 0 {
+			used++
+		}
+	}
+	for s := 0; used < 2 && s < len(counts); s++ {
+		if counts[s] == 0 {
+			counts[s] = 1
+			used++
+		}
+	}
+
+	for {
+		lengths, depth := huffmanDepths(counts)
+		if depth <= maxLength {
+			return lengths
+		}
+		// Flatten the distribution until the tree is shallow enough
+		for s, n := range counts {
+			if n > 0 {
+				counts[s] = n>>1 | 1
+			}
+		}
+	}
+}
+
+func huffmanDepths(counts []int) ([]uint8, int) {
+	type node struct {
+		weight int
+		parent int
+	}
+	var nodes []node
+	var leaves []int
+	for s, n := range counts {
+		if n > 0 {
+			leaves = append(leaves, s)
+		}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return counts[leaves[i]] < counts[leaves[j]] })
+	for _, s := range leaves {
+		nodes = append(nodes, node{weight: counts[s], parent: -1})
+	}
+
+	// Two-queue construction: leaves are sorted and internal nodes are
+	// created in non-decreasing weight order.
+	li, ii := 0, len(leaves)
+	pick := func() int {
+		if li < len(leaves) && (ii >= len(nodes) || nodes[li].weight <= nodes[ii].weight) {
+			li++
+			return li - 1
+		}
+		ii++
+		return ii - 1
+	}
+	for n := len(leaves); n > 1; n-- {
+		a, b := pick(), pick()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent = len(nodes) - 1
+		nodes[b].parent = len(nodes) - 1
+	}
+
+	lengths := make([]uint8, len(counts))
+	maxDepth := 0
+	for i, s := range leaves {
+		depth := 0
+		for p := nodes[i].parent; p != -1; p = nodes[p].parent {
+			depth++
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		lengths[s] = uint8(depth)
+	}
+	return lengths, maxDepth
+}
+
+// bitWriter packs bits least significant bit first
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v) << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,93 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name          string
+		width, height int
+		pixel         func(x, y int) color.NRGBA
+	}{
+		{"single pixel", 1, 1, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 200, G: 10, B: 30, A: 255}
+		}},
+		{"solid", 17, 9, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: 12, G: 34, B: 56, A: 255}
+		}},
+		{"gradient", 64, 48, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 4), G: uint8(y * 5), B: uint8(x + y), A: 255}
+		}},
+		{"noise", 33, 21, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(rng.Intn(256)), G: uint8(rng.Intn(256)), B: uint8(rng.Intn(256)), A: 255}
+		}},
+		{"alpha", 31, 7, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 8), G: uint8(y * 30), B: 90, A: uint8(x * y)}
+		}},
+		{"wider than a predictor block", 600, 3, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x), G: uint8(x >> 2), B: uint8(y * 70), A: 255}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					src.SetNRGBA(x, y, tt.pixel(x, y))
+				}
+			}
+
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, src); err != nil {
+				t.Fatalf("EncodeWebP: %v", err)
+			}
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			if got := decoded.Bounds(); got != src.Bounds() {
+				t.Fatalf("bounds = %v, want %v", got, src.Bounds())
+			}
+			for y := 0; y < tt.height; y++ {
+				for x := 0; x < tt.width; x++ {
+					want := src.NRGBAAt(x, y)
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if want.A == 0 {
+						// Fully transparent pixels have no defined colour
+						got.R, got.G, got.B, want.R, want.G, want.B = 0, 0, 0, 0, 0, 0
+					}
+					if got != want {
+						t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPRejectsEmptyImage(t *testing.T) {
+	if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(image.Rect(0, 0, 0, 0))); err == nil {
+		t.Fatal("EncodeWebP accepted an empty image")
+	}
+}
+
+func TestEncodeRenditionEmitsBothFormats(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	encoded, err := EncodeRendition(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(encoded) != 2 || encoded[0].Format != JPEG || encoded[1].Format != WebP {
+		t.Fatalf("formats = %v, want jpeg and webp", encoded)
+	}
+}
@@ -0,0 +1,62 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+// finishedJobRetention is how long one-off jobs are listed after they ran
+const finishedJobRetention = 7 * 24 * time.Hour
+
+// PurgeExpired returns the job deleting expired idempotency records and
+// one-off jobs that finished long ago. Registration has no verification
+// step, so there are no verification tokens or unverified attendees to
+// purge.
+func PurgeExpired(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now()
+		keys, keysErr := repo.PurgeIdempotencyKeys(ctx, now)
+		jobs, jobsErr := repo.PurgeFinishedJobs(ctx, now.Add(-finishedJobRetention))
+		if keys > 0 || jobs > 0 {
+			logging.FromContext(ctx).Info("Purged expired records", "idempotencyKeys", keys, "jobs", jobs)
+		}
+		return errors.Join(keysErr, jobsErr)
+	}
+}
+
+// SnapshotAnalytics returns the job storing the day's registration figures
+func SnapshotAnalytics(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := repo.GetAttendeeCount(ctx)
+		if err != nil {
+			return err
+		}
+		breakdown, err := repo.GetDesignationBreakdown(ctx)
+		if err != nil {
+			return err
+		}
+		sessions, err := repo.GetAllSessions(ctx)
+		if err != nil {
+			return err
+		}
+		speakers, err := repo.GetAllSpeakers(ctx)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		return repo.SaveAnalyticsSnapshot(ctx, &models.AnalyticsSnapshot{
+			Date:         now.Format(time.DateOnly),
+			Attendees:    count,
+			Designations: breakdown,
+			Sessions:     len(sessions),
+			Speakers:     len(speakers),
+			TakenAt:      now,
+		})
+	}
+}
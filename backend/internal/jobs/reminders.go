@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/mail"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+// KindSessionReminder is the one-off job emailing attendees before a
+// session starts
+const KindSessionReminder = "session-reminder"
+
+// Reminders schedules a reminder job for every session with a start time,
+// and sends the reminders when the job runs
+type Reminders struct {
+	repo      *repository.Repository
+	scheduler *Scheduler
+	sender    mail.Sender
+	lead      time.Duration
+}
+
+// NewReminders registers the reminder job with scheduler. Reminders are
+// sent lead before each session starts.
+func NewReminders(repo *repository.Repository, scheduler *Scheduler, sender mail.Sender, lead time.Duration) *Reminders {
+	r := &Reminders{repo: repo, scheduler: scheduler, sender: sender, lead: lead}
+	scheduler.Register(KindSessionReminder, r.send)
+	return r
+}
+
+func reminderJob(sessionID string) string {
+	return KindSessionReminder + ":" + sessionID
+}
+
+// HandleEvent is the outbox handler that keeps the reminder of a session
+// in line with its start time
+func (r *Reminders) HandleEvent(ctx context.Context, event *models.OutboxEvent) error {
+	switch event.Type {
+	case models.EventSessionCreated, models.EventSessionUpdated:
+	case models.EventSessionDeleted:
+		var deleted struct{ ID string }
+		if err := json.Unmarshal([]byte(event.Data), &deleted); err != nil {
+			return err
+		}
+		return r.scheduler.Cancel(ctx, reminderJob(deleted.ID))
+	default:
+		return nil
+	}
+
+	var session models.Session
+	if err := json.Unmarshal([]byte(event.Data), &session); err != nil {
+		return err
+	}
+	name := reminderJob(session.ID)
+	if session.StartsAt == nil {
+		return r.scheduler.Cancel(ctx, name)
+	}
+	at := session.StartsAt.Add(-r.lead)
+	if !at.After(time.Now()) {
+		// Too late to remind, or already reminded
+		return nil
+	}
+	return r.scheduler.Schedule(ctx, name, KindSessionReminder, at, map[string]string{
+		"session":  session.ID,
+		"startsAt": session.StartsAt.Format(time.RFC3339),
+	})
+}
+
+// send emails every attendee about the session in args, unless it was
+// deleted or moved since the reminder was scheduled
+func (r *Reminders) send(ctx context.Context, args map[string]string) error {
+	session, err := r.repo.GetSession(ctx, args["session"])
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.StartsAt == nil || session.StartsAt.Format(time.RFC3339) != args["startsAt"] {
+		return nil
+	}
+
+	attendees, err := r.repo.GetAllAttendees(ctx)
+	if err != nil {
+		return err
+	}
+	msg := mail.Message{
+		Subject: "Starting soon: " + session.Title,
+		Body:    reminderBody(session),
+	}
+	var errs []error
+	for _, attendee := range attendees {
+		msg.To = attendee.Email
+		if err := r.sender.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", attendee.ID, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d reminders failed: %w", len(errs), len(attendees), errors.Join(errs...))
+	}
+	return nil
+}
+
+func reminderBody(session *models.Session) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s starts at %s.\n\n", session.Title, session.Time)
+	b.WriteString(session.Description)
+	b.WriteString("\n\nSee you there!\n")
+	return b.String()
+}
@@ -0,0 +1,240 @@
+// Package jobs runs scheduled work in the server process. Job state is
+// kept in Firestore, and only the instance holding the scheduler lease
+// runs jobs, so each run happens on one instance even when several are
+// serving the event.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// leaseName is the lease record held by the instance running jobs
+	leaseName = "scheduler"
+	// leaseTTL is how long the lease lasts without renewal, so another
+	// instance takes over within that long after the leader stops
+	leaseTTL = time.Minute
+	// tickInterval is how often the lease is renewed and due jobs are
+	// looked for
+	tickInterval = 15 * time.Second
+	// runTimeout bounds a single run of a job
+	runTimeout = 30 * time.Minute
+)
+
+// Func runs a job with the arguments it was scheduled with
+type Func func(ctx context.Context, args map[string]string) error
+
+// recurring is a job registered with a cron schedule
+type recurring struct {
+	spec     string
+	schedule cron.Schedule
+}
+
+// Scheduler runs recurring and one-off jobs
+type Scheduler struct {
+	repo   *repository.Repository
+	holder string
+
+	kinds     map[string]Func
+	recurring map[string]recurring
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func NewScheduler(repo *repository.Repository) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		repo:      repo,
+		holder:    host + "/" + uuid.New().String(),
+		kinds:     make(map[string]Func),
+		recurring: make(map[string]recurring),
+		running:   make(map[string]bool),
+	}
+}
+
+// Register adds the code run by one-off jobs of kind. Jobs are registered
+// before Run.
+func (s *Scheduler) Register(kind string, fn Func) {
+	s.kinds[kind] = fn
+}
+
+// Every registers a recurring job run on a standard five-field cron
+// schedule, in UTC unless spec starts with CRON_TZ=
+func (s *Scheduler) Every(name, spec string, fn func(ctx context.Context) error) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", name, err)
+	}
+	s.kinds[name] = func(ctx context.Context, _ map[string]string) error { return fn(ctx) }
+	s.recurring[name] = recurring{spec: spec, schedule: schedule}
+	return nil
+}
+
+// Schedule stores a one-off job of kind to run at at, replacing any job
+// with the same name
+func (s *Scheduler) Schedule(ctx context.Context, name, kind string, at time.Time, args map[string]string) error {
+	if _, ok := s.kinds[kind]; !ok {
+		return fmt.Errorf("unknown job kind %q", kind)
+	}
+	return s.repo.ScheduleJob(ctx, &models.Job{
+		Name:      name,
+		Kind:      kind,
+		Args:      args,
+		NextRunAt: &at,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Cancel removes a one-off job that has not run yet
+func (s *Scheduler) Cancel(ctx context.Context, name string) error {
+	return s.repo.CancelJob(ctx, name)
+}
+
+// Run runs due jobs whenever this instance holds the scheduler lease,
+// until ctx is cancelled. It then waits for running jobs and gives up the
+// lease.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	leader := false
+	for {
+		acquired, err := s.repo.AcquireLease(ctx, leaseName, s.holder, leaseTTL)
+		if err != nil && ctx.Err() == nil {
+			logging.FromContext(ctx).Warn("Failed to renew the scheduler lease", "error", err)
+		}
+		if acquired && !leader {
+			logging.FromContext(ctx).Info("Running scheduled jobs on this instance")
+			s.syncRecurring(ctx)
+		}
+		leader = acquired
+		if leader {
+			s.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			if leader {
+				if err := s.repo.ReleaseLease(context.WithoutCancel(ctx), leaseName, s.holder); err != nil {
+					logging.FromContext(ctx).Warn("Failed to release the scheduler lease", "error", err)
+				}
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncRecurring stores the recurring jobs that are new or were given a
+// different schedule
+func (s *Scheduler) syncRecurring(ctx context.Context) {
+	now := time.Now()
+	for name, r := range s.recurring {
+		if err := s.repo.EnsureRecurringJob(ctx, name, r.spec, r.schedule.Next(now)); err != nil {
+			logging.FromContext(ctx).Error("Failed to store job", "job", name, "error", err)
+		}
+	}
+}
+
+// runDue starts every due job that is not already running here
+func (s *Scheduler) runDue(ctx context.Context) {
+	jobs, err := s.repo.GetJobs(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to load jobs", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range jobs {
+		job := &jobs[i]
+		if job.NextRunAt == nil || job.NextRunAt.After(now) || !s.claim(job.Name) {
+			continue
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.release(job.Name)
+			s.run(ctx, job)
+		}()
+	}
+}
+
+// run starts job if it is still due and records the outcome
+func (s *Scheduler) run(ctx context.Context, job *models.Job) {
+	var next *time.Time
+	if r, ok := s.recurring[job.Name]; ok {
+		t := r.schedule.Next(time.Now())
+		next = &t
+	}
+	logger := logging.FromContext(ctx).With("job", job.Name)
+	ctx = logging.WithLogger(ctx, logger)
+	started, err := s.repo.StartJob(ctx, job.Name, next)
+	if err != nil {
+		logger.Error("Failed to start job", "error", err)
+		return
+	}
+	if !started {
+		return
+	}
+
+	startedAt := time.Now()
+	err = s.call(ctx, job)
+	duration := time.Since(startedAt)
+	if err != nil {
+		logger.Error("Job failed", "duration", duration, "error", err)
+	} else {
+		logger.Info("Job succeeded", "duration", duration)
+	}
+
+	// Record the outcome even if the server is shutting down
+	if err := s.repo.RecordJobRun(context.WithoutCancel(ctx), job.Name, startedAt, duration, err); err != nil {
+		logger.Error("Failed to record job run", "error", err)
+	}
+}
+
+// call runs the code of job, turning a panic into an error
+func (s *Scheduler) call(ctx context.Context, job *models.Job) (err error) {
+	fn, ok := s.kinds[job.Kind]
+	if !ok {
+		return fmt.Errorf("unknown job kind %q", job.Kind)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+	return fn(ctx, job.Args)
+}
+
+func (s *Scheduler) claim(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[name] {
+		return false
+	}
+	s.running[name] = true
+	return true
+}
+
+func (s *Scheduler) release(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, name)
+}
@@ -0,0 +1,168 @@
+// Package live maintains aggregates that are pushed to connected clients
+// as they change
+package live
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// StatsTopic carries RegistrationStats snapshots to stream subscribers
+	StatsTopic = "registrations:stats"
+	// StatsEvent is the SSE event carrying a RegistrationStats snapshot
+	StatsEvent = "stats"
+
+	// statsDebounce coalesces bursts of registrations into one refresh
+	statsDebounce = 500 * time.Millisecond
+	// statsResync bounds how stale the snapshot gets when registrations
+	// arrive through another instance
+	statsResync = 30 * time.Second
+)
+
+// StatsBroadcaster keeps the latest registration stats and publishes them
+// on StatsTopic when registrations change. The stats are read once per
+// change, however many clients are connected.
+type StatsBroadcaster struct {
+	repo  *repository.Repository
+	bus   *events.Bus
+	group singleflight.Group
+
+	mu     sync.Mutex
+	latest *models.RegistrationStats
+}
+
+func NewStatsBroadcaster(repo *repository.Repository, bus *events.Bus) *StatsBroadcaster {
+	return &StatsBroadcaster{repo: repo, bus: bus}
+}
+
+// Run refreshes the stats after every change published on
+// events.TopicRegistrations, and periodically while anyone is listening,
+// until ctx is cancelled or the bus is closed
+func (b *StatsBroadcaster) Run(ctx context.Context) {
+	changes := b.bus.Subscribe(events.TopicRegistrations)
+	defer changes.Close()
+
+	resync := time.NewTicker(statsResync)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes.Events():
+			if !ok || !debounce(ctx, changes) {
+				return
+			}
+			b.refresh(ctx)
+		case <-resync.C:
+			if b.bus.Subscribers(StatsTopic) > 0 {
+				b.refresh(ctx)
+			}
+		}
+	}
+}
+
+// Snapshot returns the latest stats, loading them if they are missing or
+// stale. Concurrent callers share a single load.
+func (b *StatsBroadcaster) Snapshot(ctx context.Context) (*models.RegistrationStats, error) {
+	b.mu.Lock()
+	latest := b.latest
+	b.mu.Unlock()
+	if latest != nil && time.Since(latest.UpdatedAt) < statsResync {
+		return latest, nil
+	}
+	return b.load(ctx)
+}
+
+// debounce drains further changes until none arrive for statsDebounce. It
+// returns false if ctx is cancelled or the bus closed meanwhile.
+func debounce(ctx context.Context, changes *events.Subscription) bool {
+	timer := time.NewTimer(statsDebounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case _, ok := <-changes.Events():
+			if !ok {
+				return false
+			}
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+// refresh reloads the stats and publishes them if they changed
+func (b *StatsBroadcaster) refresh(ctx context.Context) {
+	b.mu.Lock()
+	previous := b.latest
+	b.mu.Unlock()
+
+	stats, err := b.load(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to load registration stats", "error", err)
+		return
+	}
+	if previous != nil && previous.Count == stats.Count && reflect.DeepEqual(previous.Sessions, stats.Sessions) {
+		return
+	}
+	b.bus.Publish(StatsTopic, StatsEvent, stats)
+}
+
+func (b *StatsBroadcaster) load(ctx context.Context) (*models.RegistrationStats, error) {
+	v, err, _ := b.group.Do("stats", func() (interface{}, error) {
+		stats, err := b.compute(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.mu.Lock()
+		b.latest = stats
+		b.mu.Unlock()
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*models.RegistrationStats), nil
+}
+
+func (b *StatsBroadcaster) compute(ctx context.Context) (*models.RegistrationStats, error) {
+	count, err := b.repo.GetAttendeeCount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := b.repo.GetAllSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.RegistrationStats{
+		Count:     count,
+		Sessions:  make([]models.SessionAvailability, 0, len(sessions)),
+		UpdatedAt: time.Now(),
+	}
+	for _, s := range sessions {
+		availability := models.SessionAvailability{SessionID: s.ID, Title: s.Title}
+		if s.Capacity != nil {
+			seats := *s.Capacity - count
+			if seats < 0 {
+				seats = 0
+			}
+			availability.Capacity = s.Capacity
+			availability.SeatsAvailable = &seats
+		}
+		stats.Sessions = append(stats.Sessions, availability)
+	}
+	return stats, nil
+}
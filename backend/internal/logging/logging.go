@@ -0,0 +1,112 @@
+// Package logging sets up structured logging with log/slog. Every request
+// gets a request ID and a logger carrying it, which handlers, middleware
+// and the repository take from the request context. Emails and secrets
+// are redacted from every record.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// redacted replaces the values of sensitive attributes
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys, compared case-insensitively, whose
+// values are never logged. Request headers are logged under their
+// canonical names.
+var sensitiveKeys = map[string]bool{
+	"x-admin-password": true,
+	"authorization":    true,
+	"cookie":           true,
+	"password":         true,
+	"admin_password":   true,
+	"smtp_password":    true,
+	"metrics_token":    true,
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@([A-Za-z0-9\-]+\.)+[A-Za-z]{2,}`)
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// New returns a logger writing records at level and above to w, as JSON
+// with the field names Cloud Logging expects or as key=value text
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	switch format {
+	case "", FormatJSON:
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 {
+				switch a.Key {
+				case slog.LevelKey:
+					a.Key = "severity"
+				case slog.MessageKey:
+					a.Key = "message"
+				}
+			}
+			return redact(groups, a)
+		}
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
+
+// redact hides sensitive attributes and masks emails in the others
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if s := a.Value.String(); strings.Contains(s, "@") {
+			return slog.String(a.Key, RedactEmails(s))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactEmails(err.Error()))
+		}
+	}
+	return a
+}
+
+// RedactEmails masks the local part of every email address in s, keeping
+// the domain
+func RedactEmails(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, func(email string) string {
+		return "***" + email[strings.LastIndex(email, "@"):]
+	})
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
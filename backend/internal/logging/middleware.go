@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID of a request, from the caller if it sent
+// one and back in the response
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestID bounds the length of an incoming request ID
+const maxRequestID = 128
+
+// probeRoutes are polled by load balancers and scrapers; successful
+// requests to them are only logged at debug level
+var probeRoutes = map[string]bool{"/health": true, "/livez": true, "/readyz": true, "/metrics": true}
+
+// Middleware gives every request a request ID and a logger derived from
+// logger that carries it, along with the trace ID when the request is
+// traced, and logs each request when it completes. It runs after the
+// tracing middleware.
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, id)
+
+		requestLogger := logger.With("requestId", id)
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			requestLogger = requestLogger.With("traceId", span.TraceID().String(), "spanId", span.SpanID().String())
+		}
+		ctx := WithLogger(c.Request.Context(), requestLogger)
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case probeRoutes[c.FullPath()]:
+			level = slog.LevelDebug
+		}
+		if !requestLogger.Enabled(ctx, level) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("clientIp", c.ClientIP()),
+			slog.String("userAgent", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		if requestLogger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, headerGroup(c.Request.Header))
+		}
+		requestLogger.LogAttrs(ctx, level, "Request handled", attrs...)
+	}
+}
+
+// Recovery turns a panic in a handler into a 500 response and logs it
+// with the request's logger
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+		FromContext(c.Request.Context()).Error("Handler panicked", "panic", recovered)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// headerGroup logs request headers by name; sensitive ones are redacted
+func headerGroup(header http.Header) slog.Attr {
+	attrs := make([]any, 0, len(header))
+	for name, values := range header {
+		value := values[0]
+		if len(values) > 1 {
+			value = fmt.Sprint(values)
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// validRequestID accepts short IDs of printable ASCII, so a caller cannot
+// forge log lines through the header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestID {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+// Package mail sends plain-text emails to attendees
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+)
+
+// Message is a plain-text email to one recipient
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends emails
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender sends emails through an SMTP server, with STARTTLS when the
+// server offers it
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender returns a sender for the server at addr (host:port). It
+// authenticates when username is set.
+func NewSMTPSender(addr, username, password, from string) (*SMTPSender, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP address %q: %w", addr, err)
+	}
+	s := &SMTPSender{addr: addr, from: from}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, s.format(msg))
+}
+
+// format returns msg with its headers, as sent over SMTP
+func (s *SMTPSender) format(msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(msg.Body)
+	return b.Bytes()
+}
+
+// LogSender logs emails instead of sending them, for development and for
+// deployments without an SMTP server
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	logging.FromContext(ctx).Info("Email not sent (SMTP not configured)", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package metrics exposes Prometheus metrics for HTTP requests, repository
+// operations, caches and registrations
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statsTimeout bounds loading the registration stats during a scrape
+const statsTimeout = 5 * time.Second
+
+var (
+	registry = prometheus.NewRegistry()
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	repositoryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "repository_operation_duration_seconds",
+		Help:    "Repository operation latency by method.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method"})
+	repositoryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "repository_operation_errors_total",
+		Help: "Failed repository operations by method and error kind.",
+	}, []string{"method", "kind"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_lookups_total",
+		Help: "Cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requests, requestDuration, repositoryDuration, repositoryErrors, cacheLookups,
+	)
+}
+
+// Middleware counts and times every request by its route pattern, e.g.
+// /api/sessions/:id, so IDs do not create new series. Requests matching no
+// route are counted under "unmatched".
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveRepository records a repository call to method that took d and
+// failed with an error of kind, or succeeded if kind is empty
+func ObserveRepository(method string, d time.Duration, kind string) {
+	repositoryDuration.WithLabelValues(method).Observe(d.Seconds())
+	if kind != "" {
+		repositoryErrors.WithLabelValues(method, kind).Inc()
+	}
+}
+
+// ObserveCache records a lookup in the named cache
+func ObserveCache(name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(name, result).Inc()
+}
+
+// RegisterStats adds gauges of registered attendees and of each session's
+// fill ratio, read from stats when scraped
+func RegisterStats(stats func(ctx context.Context) (*models.RegistrationStats, error)) {
+	registry.MustRegister(&statsCollector{stats: stats})
+}
+
+// Handler serves the metrics in the Prometheus text format. If token is
+// set, scrapers must send it as a bearer token. A collector that fails
+// leaves out its metrics rather than failing the scrape.
+func Handler(token string) gin.HandlerFunc {
+	serve := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog:      slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+	return func(c *gin.Context) {
+		if token != "" {
+			want := []byte("Bearer " + token)
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), want) != 1 {
+				c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+		serve.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+var (
+	attendeesDesc = prometheus.NewDesc("workshop_registered_attendees",
+		"Registered attendees.", nil, nil)
+	fillRatioDesc = prometheus.NewDesc("workshop_session_fill_ratio",
+		"Registered attendees per seat of each session with a capacity.", []string{"session_id", "title"}, nil)
+)
+
+// statsCollector reports the registration stats at scrape time. When they
+// cannot be loaded the gauges are left out of that scrape.
+type statsCollector struct {
+	stats func(ctx context.Context) (*models.RegistrationStats, error)
+}
+
+func (s *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- attendeesDesc
+	ch <- fillRatioDesc
+}
+
+func (s *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+	stats, err := s.stats(ctx)
+	if err != nil {
+		slog.Warn("Failed to load registration stats for metrics", "error", err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(attendeesDesc, prometheus.GaugeValue, float64(stats.Count))
+	for _, session := range stats.Sessions {
+		if session.Capacity == nil || *session.Capacity <= 0 {
+			continue
+		}
+		ratio := float64(stats.Count) / float64(*session.Capacity)
+		ch <- prometheus.MustNewConstMetric(fillRatioDesc, prometheus.GaugeValue, ratio, session.SessionID, session.Title)
+	}
+}
@@ -1,27 +1,58 @@
 package middleware
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"net/http"
-	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AdminAuth middleware validates admin password
-func AdminAuth() gin.HandlerFunc {
+// adminCredentialTTL is how long verified admin user credentials are
+// trusted without checking them again. Deleting an admin or changing their
+// password takes effect on this instance within it.
+const adminCredentialTTL = 30 * time.Second
+
+// AdminAuth middleware validates the shared admin password, or the
+// credentials of an admin user when X-Admin-Email is set. Admin user
+// credentials are checked against Firestore and their bcrypt hash at most
+// once per adminCredentialTTL.
+func AdminAuth(repo *repository.Repository, adminPassword string) gin.HandlerFunc {
+	verified := &credentialCache{expires: make(map[[sha256.Size]byte]time.Time)}
+
 	return func(c *gin.Context) {
 		password := c.GetHeader("X-Admin-Password")
-		expectedPassword := os.Getenv("ADMIN_PASSWORD")
 
-		if expectedPassword == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Admin password not configured"})
-			c.Abort()
+		if email := c.GetHeader("X-Admin-Email"); email != "" {
+			key := credentialKey(email, password)
+			if verified.contains(key) {
+				c.Next()
+				return
+			}
+
+			_, err := repo.AuthenticateAdmin(c.Request.Context(), email, password)
+			if errors.Is(err, repository.ErrInvalidCredentials) {
+				problem.Write(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid admin credentials")
+				return
+			}
+			if err != nil {
+				problem.Error(c, err)
+				return
+			}
+			verified.add(key)
+			c.Next()
 			return
 		}
 
-		if password != expectedPassword {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin password"})
-			c.Abort()
+		if subtle.ConstantTimeCompare([]byte(password), []byte(adminPassword)) != 1 {
+			problem.Write(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid admin password")
 			return
 		}
 
@@ -29,3 +60,33 @@ func AdminAuth() gin.HandlerFunc {
 	}
 }
 
+// credentialKey identifies an email and password pair without keeping the
+// password itself
+func credentialKey(email, password string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email)) + "\x00" + password))
+}
+
+// credentialCache holds recently verified credentials until they expire
+type credentialCache struct {
+	mu      sync.Mutex
+	expires map[[sha256.Size]byte]time.Time
+}
+
+func (c *credentialCache) contains(key [sha256.Size]byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Before(c.expires[key])
+}
+
+// add remembers key for adminCredentialTTL, dropping expired entries
+func (c *credentialCache) add(key [sha256.Size]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, expires := range c.expires {
+		if !now.Before(expires) {
+			delete(c.expires, k)
+		}
+	}
+	c.expires[key] = now.Add(adminCredentialTTL)
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize is the smallest response worth compressing
+const minCompressSize = 1024
+
+// Content codings, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, 5)
+	}}
+)
+
+// compressible reports whether responses of a content type are worth
+// compressing. Images are already compressed, and event streams must reach
+// clients as each event is written.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return mediaType != "text/event-stream"
+	case mediaType == "application/json",
+		strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/javascript",
+		mediaType == "application/xml",
+		mediaType == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// Compress compresses responses with brotli or gzip, whichever the client
+// prefers in Accept-Encoding (brotli on a tie). Only textual responses of
+// at least 1 KiB are compressed; others, partial content and responses
+// marked no-transform pass through unchanged. Entity tags are left as
+// they are, so If-Match works whatever the encoding.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &compressWriter{ResponseWriter: original, encoding: encoding}
+		c.Writer = w
+		defer func() {
+			w.finish()
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the coding to use for an Accept-Encoding header,
+// or "" for none
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name != encodingBrotli && name != encodingGzip || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter holds back the start of the response until it knows
+// whether to compress it: once minCompressSize bytes are written, or the
+// handler flushes or returns.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+
+	buf     bytes.Buffer
+	decided bool
+	writer  io.WriteCloser // set when compressing
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.decided {
+		if w.writer != nil {
+			return w.writer.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() >= minCompressSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers once the response is decided; until
+// then they may still change to announce compression
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided && w.ResponseWriter.Written() || w.buf.Len() > 0
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if f, ok := w.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide starts compressing if the response is worth it, then writes what
+// was held back
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if w.buf.Len() >= minCompressSize && header.Get("Content-Encoding") == "" &&
+		status != http.StatusPartialContent && header.Get("Content-Range") == "" &&
+		!strings.Contains(header.Get("Cache-Control"), "no-transform") &&
+		compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Add("Vary", "Accept-Encoding")
+		switch w.encoding {
+		case encodingBrotli:
+			bw := brotliWriters.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.writer = bw
+		default:
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.writer = gw
+		}
+	} else if compressible(header.Get("Content-Type")) {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.writer != nil {
+		_, err = w.writer.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// finish writes what is still held back and ends the compressed stream
+func (w *compressWriter) finish() {
+	if !w.decided {
+		if w.buf.Len() == 0 {
+			// Nothing written; leave the headers to gin
+			return
+		}
+		w.decide()
+	}
+	switch cw := w.writer.(type) {
+	case *brotli.Writer:
+		cw.Close()
+		cw.Reset(io.Discard)
+		brotliWriters.Put(cw)
+	case *gzip.Writer:
+		cw.Close()
+		cw.Reset(io.Discard)
+		gzipWriters.Put(cw)
+	}
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxIdempotencyKey bounds the length of an Idempotency-Key header
+	maxIdempotencyKey = 255
+	// maxIdempotentBody bounds the request body read to fingerprint it
+	maxIdempotentBody = 1 << 20
+	// maxStoredResponse bounds the response body kept for replay; larger
+	// responses are not stored
+	maxStoredResponse = 512 << 10
+	// idempotencyLease is how long a claim blocks retries before another
+	// request may take over a key whose first request never finished
+	idempotencyLease = 30 * time.Second
+	// idempotencyWait is how long a retry waits for a request with the
+	// same key running on another instance
+	idempotencyWait = 10 * time.Second
+)
+
+// replayedHeaders are the response headers stored with a response and
+// sent again when it is replayed
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyStore keeps idempotency records. *repository.Repository
+// implements it.
+type IdempotencyStore interface {
+	ClaimIdempotencyKey(ctx context.Context, key, fingerprint string, lease, ttl time.Duration) (*models.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) error
+}
+
+var _ IdempotencyStore = (*repository.Repository)(nil)
+
+// Idempotency makes POST requests that send an Idempotency-Key header safe
+// to retry. The first response with a status below 500 is stored for ttl
+// and replayed, with Idempotent-Replayed: true, to later requests with the
+// same key and body. A different body with the same key is rejected with
+// 422. Requests with the same key are serialized: the second waits for the
+// first to finish. Requests without the header are passed through.
+func Idempotency(repo IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	locks := &keyLocks{locks: make(map[string]*keyLock)}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			problem.Invalid(c, "Idempotency-Key", "max", "must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBody))
+		if err != nil {
+			problem.Bind(c, err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Keys are scoped to the endpoint, and the body is fingerprinted to
+		// catch a key reused for a different request
+		id := hash(c.Request.Method, c.Request.URL.Path, key)
+		fingerprint := hash(string(body))
+
+		unlock := locks.lock(id)
+		defer unlock()
+
+		ctx := c.Request.Context()
+		deadline := time.Now().Add(idempotencyWait)
+		for {
+			record, claimed, err := repo.ClaimIdempotencyKey(ctx, id, fingerprint, idempotencyLease, ttl)
+			if err != nil {
+				problem.Error(c, err)
+				return
+			}
+			switch {
+			case claimed:
+				handleIdempotent(c, repo, id, record)
+				return
+			case record.Fingerprint != fingerprint:
+				problem.Write(c, http.StatusUnprocessableEntity, problem.CodeIdempotencyMismatch,
+					"This Idempotency-Key was already used with a different request body")
+				return
+			case record.Completed:
+				replay(c, record)
+				return
+			}
+
+			// The first request is still running on another instance
+			if time.Now().After(deadline) {
+				c.Header("Retry-After", "1")
+				problem.Write(c, http.StatusConflict, problem.CodeIdempotencyInProgress,
+					"A request with this Idempotency-Key is still being processed")
+				return
+			}
+			select {
+			case <-ctx.Done():
+				problem.Error(c, ctx.Err())
+				return
+			case <-time.After(250 * time.Millisecond):
+			}
+		}
+	}
+}
+
+// handleIdempotent runs the handler for a claimed key and stores its
+// response, or releases the key if the request failed on the server
+func handleIdempotent(c *gin.Context, repo IdempotencyStore, id string, record *models.IdempotencyRecord) {
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Next()
+
+	// Store the outcome even if the client has gone away
+	ctx := context.WithoutCancel(c.Request.Context())
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError || recorder.overflow {
+		if err := repo.ReleaseIdempotencyKey(ctx, id, record); err != nil {
+			logging.FromContext(ctx).Error("Failed to release idempotency key", "error", err)
+		}
+		return
+	}
+
+	record.Status = status
+	record.Body = recorder.body.Bytes()
+	record.Header = make(map[string]string, len(replayedHeaders))
+	for _, name := range replayedHeaders {
+		if v := recorder.Header().Get(name); v != "" {
+			record.Header[name] = v
+		}
+	}
+	err := repo.CompleteIdempotencyKey(ctx, id, record)
+	if errors.Is(err, repository.ErrClaimLost) {
+		logging.FromContext(ctx).Warn("Idempotency key was taken over before the response was stored", "status", status)
+	} else if err != nil {
+		logging.FromContext(ctx).Error("Failed to store idempotent response", "error", err)
+	}
+}
+
+// replay writes a stored response
+func replay(c *gin.Context, record *models.IdempotencyRecord) {
+	for name, value := range record.Header {
+		c.Header(name, value)
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(record.Status, record.Header["Content-Type"], record.Body)
+	c.Abort()
+}
+
+// hash returns the hex SHA-256 of parts separated by newlines
+func hash(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		io.WriteString(h, part)
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *responseRecorder) record(b []byte) {
+	if w.overflow || w.body.Len()+len(b) > maxStoredResponse {
+		w.overflow = true
+		return
+	}
+	w.body.Write(b)
+}
+
+// keyLocks serializes requests with the same key within this instance, so
+// they wait on a mutex rather than polling Firestore
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lock locks key and returns the function that unlocks it
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.refs++
+	l.mu.Unlock()
+
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.mu.Lock()
+		kl.refs--
+		if kl.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotency is an IdempotencyStore with the same claim rules as
+// the repository
+type memoryIdempotency struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+	claims  int
+}
+
+func newMemoryIdempotency() *memoryIdempotency {
+	return &memoryIdempotency{records: make(map[string]models.IdempotencyRecord)}
+}
+
+func (m *memoryIdempotency) ClaimIdempotencyKey(ctx context.Context, key, fingerprint string, lease, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if existing, ok := m.records[key]; ok && now.Before(existing.ExpiresAt) && (existing.Completed || now.Before(existing.LockedUntil)) {
+		return &existing, false, nil
+	}
+	m.claims++
+	record := models.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		Claim:       "claim-" + strconv.Itoa(m.claims),
+		LockedUntil: now.Add(lease),
+		ExpiresAt:   now.Add(ttl),
+	}
+	m.records[key] = record
+	return &record, true, nil
+}
+
+func (m *memoryIdempotency) CompleteIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.records[key]; !ok || stored.Completed || stored.Claim != record.Claim {
+		return repository.ErrClaimLost
+	}
+	completed := *record
+	completed.Completed = true
+	m.records[key] = completed
+	return nil
+}
+
+func (m *memoryIdempotency) ReleaseIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.records[key]; ok && !stored.Completed && stored.Claim == record.Claim {
+		delete(m.records, key)
+	}
+	return nil
+}
+
+// only returns the single stored record
+func (m *memoryIdempotency) only(t *testing.T) (string, models.IdempotencyRecord) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.records) != 1 {
+		t.Fatalf("store has %d records, want 1", len(m.records))
+	}
+	for key, record := range m.records {
+		return key, record
+	}
+	panic("unreachable")
+}
+
+func idempotentRouter(store IdempotencyStore, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/items", Idempotency(store, time.Hour), handler)
+	return router
+}
+
+func post(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyParallelRequestsRunOnce(t *testing.T) {
+	var calls atomic.Int32
+	router := idempotentRouter(newMemoryIdempotency(), func(c *gin.Context) {
+		n := calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	const requests = 8
+	responses := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = post(router, "same-key", `{"name":"a"}`)
+		}(i)
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+	replayed := 0
+	for _, w := range responses {
+		if w.Code != http.StatusCreated || w.Body.String() != `{"call":1}` {
+			t.Errorf("response = %d %s, want 201 {\"call\":1}", w.Code, w.Body)
+		}
+		if w.Header().Get("Idempotent-Replayed") == "true" {
+			replayed++
+		}
+	}
+	if replayed != requests-1 {
+		t.Errorf("%d responses were replayed, want %d", replayed, requests-1)
+	}
+}
+
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	var calls atomic.Int32
+	router := idempotentRouter(newMemoryIdempotency(), func(c *gin.Context) {
+		calls.Add(1)
+		c.Status(http.StatusCreated)
+	})
+
+	if w := post(router, "k", `{"name":"a"}`); w.Code != http.StatusCreated {
+		t.Fatalf("first request: %d", w.Code)
+	}
+	w := post(router, "k", `{"name":"b"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("different body: %d, want 422", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"`+problem.CodeIdempotencyMismatch+`"`) {
+		t.Errorf("body = %s, want code %s", w.Body, problem.CodeIdempotencyMismatch)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	store := newMemoryIdempotency()
+	var calls atomic.Int32
+	router := idempotentRouter(store, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	if w := post(router, "k", "{}"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("first request: %d, want 500", w.Code)
+	}
+	w := post(router, "k", "{}")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("retry: %d replayed=%q, want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if _, record := store.only(t); !record.Completed || record.Status != http.StatusCreated {
+		t.Errorf("stored record = %+v, want completed 201", record)
+	}
+}
+
+func TestIdempotencyTakesOverLapsedLease(t *testing.T) {
+	store := newMemoryIdempotency()
+	router := idempotentRouter(store, func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	// A request on another instance claimed the key and never finished
+	post(router, "k", "{}")
+	key, record := store.only(t)
+	store.records[key] = models.IdempotencyRecord{
+		Fingerprint: record.Fingerprint,
+		Claim:       "dead",
+		LockedUntil: time.Now().Add(-time.Second),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	w := post(router, "k", "{}")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("takeover: %d replayed=%q, want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if _, record := store.only(t); !record.Completed || record.Claim == "dead" {
+		t.Errorf("stored record = %+v, want completed under a new claim", record)
+	}
+}
+
+func TestIdempotencyDoesNotCompleteLostClaim(t *testing.T) {
+	store := newMemoryIdempotency()
+	var taken models.IdempotencyRecord
+	router := idempotentRouter(store, func(c *gin.Context) {
+		// The lease lapses while the handler runs and another request
+		// takes over the key
+		store.mu.Lock()
+		for key, record := range store.records {
+			record.Claim = "other"
+			store.records[key] = record
+			taken = record
+		}
+		store.mu.Unlock()
+		c.Status(http.StatusCreated)
+	})
+
+	if w := post(router, "k", "{}"); w.Code != http.StatusCreated {
+		t.Fatalf("request: %d", w.Code)
+	}
+	if _, record := store.only(t); record.Completed || record.Claim != taken.Claim {
+		t.Errorf("stored record = %+v, want the other request's pending claim", record)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InvalidateOnWrite calls invalidate after every request that may have
+// written data, i.e. any successful request other than GET and HEAD, so
+// cached reads reflect the change at once. It runs before the response is
+// sent, so a client that reads right after its own write never gets the
+// stale copy.
+func InvalidateOnWrite(invalidate func()) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &invalidatingWriter{ResponseWriter: original, invalidate: invalidate}
+		c.Writer = w
+		defer func() { c.Writer = original }()
+		c.Next()
+		// For responses the handler left to gin to send, without a body
+		w.beforeSend()
+	}
+}
+
+// invalidatingWriter invalidates before the response headers go out if
+// the status is a success
+type invalidatingWriter struct {
+	gin.ResponseWriter
+	invalidate func()
+	done       bool
+}
+
+func (w *invalidatingWriter) beforeSend() {
+	if w.done {
+		return
+	}
+	w.done = true
+	if w.Status() < http.StatusBadRequest {
+		w.invalidate()
+	}
+}
+
+func (w *invalidatingWriter) Write(p []byte) (int, error) {
+	w.beforeSend()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *invalidatingWriter) WriteString(s string) (int, error) {
+	w.beforeSend()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *invalidatingWriter) WriteHeaderNow() {
+	w.beforeSend()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *invalidatingWriter) Flush() {
+	w.beforeSend()
+	w.ResponseWriter.Flush()
+}
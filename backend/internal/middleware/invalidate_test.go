@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInvalidateOnWrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		handler gin.HandlerFunc
+		want    bool
+	}{
+		{"write with body", http.MethodPost, func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"id": "1"}) }, true},
+		{"write without body", http.MethodDelete, func(c *gin.Context) { c.Status(http.StatusNoContent) }, true},
+		{"failed write", http.MethodPut, func(c *gin.Context) { c.JSON(http.StatusConflict, gin.H{}) }, false},
+		{"read", http.MethodGet, func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{}) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			w := httptest.NewRecorder()
+			invalidated, sentBefore := false, false
+			router := gin.New()
+			router.Use(InvalidateOnWrite(func() {
+				invalidated = true
+				sentBefore = w.Code != http.StatusOK || w.Body.Len() > 0
+			}))
+			router.Handle(tt.method, "/items", tt.handler)
+
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, "/items", nil))
+			if invalidated != tt.want {
+				t.Fatalf("invalidated = %v, want %v", invalidated, tt.want)
+			}
+			if sentBefore {
+				t.Error("invalidated after the response was sent")
+			}
+		})
+	}
+}
@@ -4,10 +4,10 @@ import "time"
 
 // Attendee represents an event attendee
 type Attendee struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name" binding:"required"`
-	Email       string    `json:"email" binding:"required,email"`
-	Designation string    `json:"designation" binding:"required"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name" binding:"required"`
+	Email        string    `json:"email" binding:"required,email"`
+	Designation  string    `json:"designation" binding:"required"`
 	RegisteredAt time.Time `json:"registeredAt"`
 }
 
@@ -18,6 +18,27 @@ type Speaker struct {
 	Bio      string   `json:"bio" binding:"required"`
 	PhotoURL string   `json:"photoUrl,omitempty"`
 	Sessions []string `json:"sessions,omitempty"`
+	// Photo is set by the photo upload endpoint and cannot be written directly
+	Photo *SpeakerPhoto `json:"photo,omitempty"`
+	// Version is incremented on every write and served as the ETag
+	Version int64 `json:"version"`
+}
+
+// SpeakerPhoto describes the stored renditions of an uploaded speaker photo
+type SpeakerPhoto struct {
+	// KeyPrefix is the blob store prefix holding every rendition
+	KeyPrefix  string           `json:"-"`
+	Renditions []PhotoRendition `json:"renditions"`
+	UploadedAt time.Time        `json:"uploadedAt"`
+}
+
+// PhotoRendition is one resized version of a speaker photo. URLs maps an
+// image format ("jpeg", "webp") to the URL serving it.
+type PhotoRendition struct {
+	Name   string            `json:"name"`
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	URLs   map[string]string `json:"urls"`
 }
 
 // Session represents an event session
@@ -28,6 +49,12 @@ type Session struct {
 	Time        string   `json:"time" binding:"required"`
 	SpeakerIDs  []string `json:"speakerIds" binding:"required"`
 	Capacity    *int     `json:"capacity,omitempty"`
+	// StartsAt and EndsAt are the machine-readable schedule; Time remains
+	// the display text
+	StartsAt *time.Time `json:"startsAt,omitempty"`
+	EndsAt   *time.Time `json:"endsAt,omitempty"`
+	// Version is incremented on every write and served as the ETag
+	Version int64 `json:"version"`
 }
 
 // SessionWithSpeakers includes full speaker details
@@ -36,20 +63,428 @@ type SessionWithSpeakers struct {
 	Speakers []Speaker `json:"speakers"`
 }
 
+// SpeakerWithSessions includes the speaker's sessions in place of their
+// IDs
+type SpeakerWithSessions struct {
+	Speaker
+	Sessions []Session `json:"sessions"`
+}
+
 // DesignationBreakdown represents analytics data
 type DesignationBreakdown struct {
 	Designation string `json:"designation"`
 	Count       int    `json:"count"`
 }
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Feedback is an attendee's rating of a session. Attendees submit at most
+// one per session, and feedback is reported without attendee details.
+type Feedback struct {
+	ID          string    `json:"-"`
+	SessionID   string    `json:"sessionId"`
+	AttendeeID  string    `json:"-"`
+	Rating      int       `json:"rating"`
+	Comment     string    `json:"comment,omitempty"`
+	SubmittedAt time.Time `json:"submittedAt"`
+}
+
+// FeedbackSubmission is the request body for submitting session feedback
+type FeedbackSubmission struct {
+	Email   string `json:"email" binding:"required,email"`
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// FeedbackSummary aggregates the feedback for a session or speaker.
+// Distribution holds the number of ratings from 1 to 5 in order.
+type FeedbackSummary struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Responses     int        `json:"responses"`
+	AverageRating float64    `json:"averageRating"`
+	Distribution  [5]int     `json:"distribution"`
+	Comments      []Feedback `json:"comments,omitempty"`
+}
+
+// Question is an audience question asked during a session
+type Question struct {
+	ID         string     `json:"id"`
+	SessionID  string     `json:"sessionId"`
+	AttendeeID string     `json:"-"`
+	AuthorName string     `json:"authorName,omitempty"`
+	Text       string     `json:"text"`
+	Upvotes    int        `json:"upvotes"`
+	Hidden     bool       `json:"hidden"`
+	Answered   bool       `json:"answered"`
+	Pinned     bool       `json:"pinned"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+}
+
+// QuestionSubmission is the request body for asking a question. Anonymous
+// questions omit the name.
+type QuestionSubmission struct {
+	Email string `json:"email" binding:"required,email"`
+	Name  string `json:"name" binding:"max=100"`
+	Text  string `json:"text" binding:"required,max=500"`
+}
+
+// QuestionUpvote is the request body for upvoting a question
+type QuestionUpvote struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// QuestionModeration changes the moderation state of a question. Omitted
+// fields are left unchanged.
+type QuestionModeration struct {
+	Hidden   *bool `json:"hidden"`
+	Answered *bool `json:"answered"`
+	Pinned   *bool `json:"pinned"`
+}
+
+// Poll statuses
+const (
+	PollDraft  = "draft"
+	PollOpen   = "open"
+	PollClosed = "closed"
+)
+
+// Poll is a single or multiple choice poll attached to a session. Votes
+// maps option IDs to the number of votes received and Voters counts the
+// attendees who voted.
+type Poll struct {
+	ID        string         `json:"id"`
+	SessionID string         `json:"sessionId"`
+	Question  string         `json:"question"`
+	Options   []PollOption   `json:"options"`
+	Multiple  bool           `json:"multiple"`
+	Status    string         `json:"status"`
+	Votes     map[string]int `json:"votes"`
+	Voters    int            `json:"voters"`
+	CreatedAt time.Time      `json:"createdAt"`
+	OpenedAt  *time.Time     `json:"openedAt,omitempty"`
+	ClosedAt  *time.Time     `json:"closedAt,omitempty"`
+}
+
+// PollOption is one answer of a poll
+type PollOption struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// PollInput is the request body for creating a poll
+type PollInput struct {
+	Question string   `json:"question" binding:"required,max=300"`
+	Options  []string `json:"options" binding:"required,min=2,max=10,dive,required,max=200"`
+	Multiple bool     `json:"multiple"`
+}
+
+// PollVote is the request body for voting in a poll. Single choice polls
+// take exactly one option.
+type PollVote struct {
+	Email     string   `json:"email" binding:"required,email"`
+	OptionIDs []string `json:"optionIds" binding:"required,min=1"`
+}
+
+// SessionPollResults groups the results of a session's polls for analytics
+type SessionPollResults struct {
+	SessionID    string `json:"sessionId"`
+	SessionTitle string `json:"sessionTitle"`
+	Polls        []Poll `json:"polls"`
+}
+
+// RegistrationStats is the live registration count and the remaining seats
+// of each session
+type RegistrationStats struct {
+	Count     int                   `json:"count"`
+	Sessions  []SessionAvailability `json:"sessions"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+}
+
+// SessionAvailability reports the seats left in a session. The workshop is
+// single track, so every registered attendee takes a seat in every session.
+// Sessions without a capacity have unlimited seats and omit both counts.
+type SessionAvailability struct {
+	SessionID      string `json:"sessionId"`
+	Title          string `json:"title"`
+	Capacity       *int   `json:"capacity,omitempty"`
+	SeatsAvailable *int   `json:"seatsAvailable,omitempty"`
+}
+
+// TrashItem is a soft-deleted attendee, speaker or session. Kind is
+// "attendees", "speakers" or "sessions" and Item holds the entity as it was
+// when deleted. It is purged permanently at PurgeAt.
+type TrashItem struct {
+	ID        string      `json:"id"`
+	Kind      string      `json:"kind"`
+	DeletedAt time.Time   `json:"deletedAt"`
+	PurgeAt   time.Time   `json:"purgeAt"`
+	Item      interface{} `json:"item"`
+}
+
+// EventArchive is a backup of an event's whole Firestore tree. Settings
+// holds the fields of the event document itself. Field values keep their
+// Firestore types: integers are JSON numbers, while timestamps, doubles and
+// bytes are objects with a single "$timestamp", "$double" or "$bytes" key.
+type EventArchive struct {
+	Format      string                       `json:"format"`
+	Version     int                          `json:"version"`
+	EventID     string                       `json:"eventId"`
+	ExportedAt  time.Time                    `json:"exportedAt"`
+	Settings    map[string]interface{}       `json:"settings,omitempty"`
+	Collections map[string][]ArchiveDocument `json:"collections"`
+}
+
+// ArchiveDocument is a document and its subcollections. Data is omitted
+// for documents that only exist as the parent of subcollections.
+type ArchiveDocument struct {
+	ID          string                       `json:"id"`
+	Data        map[string]interface{}       `json:"data,omitempty"`
+	Collections map[string][]ArchiveDocument `json:"collections,omitempty"`
+}
+
+// Conflict strategies for restoring a document that already exists
+const (
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+// RestoreReport describes what restoring an archive did, or would do for a
+// dry run. Conflicts lists the paths of documents that already existed.
+type RestoreReport struct {
+	EventID     string   `json:"eventId"`
+	DryRun      bool     `json:"dryRun"`
+	OnConflict  string   `json:"onConflict"`
+	Created     int      `json:"created"`
+	Overwritten int      `json:"overwritten"`
+	Skipped     int      `json:"skipped"`
+	Conflicts   []string `json:"conflicts,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// Page is the response of the admin list endpoints. Total counts every
+// item matching the filters; NextCursor is empty on the last page.
+type Page struct {
+	Items      interface{} `json:"items"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"nextCursor"`
+}
+
+// Problem is an RFC 7807 problem details error response, sent as
+// application/problem+json. Code is a stable machine-readable error code;
+// Errors lists the invalid fields of a request.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request. Field is the JSON
+// path of the field, e.g. "options[1]", and Rule the validation rule it
+// failed, e.g. "required" or "max".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
 // SuccessResponse represents a success response
 type SuccessResponse struct {
-	Message string `json:"message"`
+	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// AdminUser is a named admin account. Admin users are shared by every event
+// and authenticate with X-Admin-Email and X-Admin-Password.
+type AdminUser struct {
+	Email        string    `json:"email"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// MigrationStatus reports whether a data migration has run for an event
+type MigrationStatus struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	AppliedAt   *time.Time `json:"appliedAt,omitempty"`
+}
+
+// IdempotencyRecord is the outcome of a request sent with an
+// Idempotency-Key header, kept so retries get the same response. It is
+// pending, with Completed false, while the first request runs. Claim
+// identifies the request holding the key.
+type IdempotencyRecord struct {
+	Fingerprint string            `json:"fingerprint"`
+	Claim       string            `json:"claim"`
+	Completed   bool              `json:"completed"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	LockedUntil time.Time         `json:"lockedUntil"`
+	ExpiresAt   time.Time         `json:"expiresAt"`
+}
+
+// Domain event types, written to the outbox with the change they describe
+const (
+	EventAttendeeRegistered = "attendee.registered"
+	EventAttendeeDeleted    = "attendee.deleted"
+	EventSessionCreated     = "session.created"
+	EventSessionUpdated     = "session.updated"
+	EventSessionDeleted     = "session.deleted"
+	EventSpeakerCreated     = "speaker.created"
+	EventSpeakerUpdated     = "speaker.updated"
+	EventSpeakerDeleted     = "speaker.deleted"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes, and kept until every side effect has been handled.
+// Data is the JSON of the changed entity, or {"id": ...} for deletions.
+// Events are leased to one instance at a time until LeasedUntil.
+type OutboxEvent struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Data        string    `json:"data"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Attempts    int       `json:"attempts"`
+	LeasedUntil time.Time `json:"leasedUntil"`
+	Error       string    `json:"error,omitempty"`
+	// DeadAt is set when the event was given up on and moved to the dead
+	// letters
+	DeadAt *time.Time `json:"deadAt,omitempty"`
+}
+
+// Webhook is an admin-configured endpoint that receives the events it
+// subscribes to as signed POST requests. Secret is only returned when the
+// webhook is created. Webhooks that keep failing are disabled.
+type Webhook struct {
+	ID                  string     `json:"id"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Description         string     `json:"description,omitempty"`
+	Secret              string     `json:"secret,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	FailingSince        *time.Time `json:"failingSince,omitempty"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	DisabledReason      string     `json:"disabledReason,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+}
+
+// WebhookInput is the request body for creating or updating a webhook
+type WebhookInput struct {
+	URL         string   `json:"url" binding:"required,http_url,max=2048"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=attendee.registered attendee.deleted session.created session.updated session.deleted speaker.created speaker.updated speaker.deleted"`
+	Description string   `json:"description" binding:"max=200"`
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of
+// its latest attempt. Pending deliveries are retried at NextAttemptAt.
+// Payload is the exact JSON body that is signed and sent.
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	WebhookID      string     `json:"webhookId"`
+	EventID        string     `json:"eventId"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	ReplayOf       string     `json:"replayOf,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt,omitempty"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+}
+
+// WebhookPayload is the body of a webhook request. ID identifies the
+// event, so receivers can drop retried and replayed duplicates.
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Workshop  string      `json:"workshop"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Job run statuses
+const (
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is the persisted state of a scheduled job. Recurring jobs have a
+// cron Schedule; one-off jobs have none and run once at NextRunAt. Kind
+// names the code that runs the job, with Args as its input. NextRunAt is
+// nil once a one-off job has run.
+type Job struct {
+	Name         string            `json:"name"`
+	Kind         string            `json:"kind"`
+	Schedule     string            `json:"schedule,omitempty"`
+	Args         map[string]string `json:"args,omitempty"`
+	NextRunAt    *time.Time        `json:"nextRunAt,omitempty"`
+	LastRunAt    *time.Time        `json:"lastRunAt,omitempty"`
+	LastDuration float64           `json:"lastDurationSeconds,omitempty"`
+	LastStatus   string            `json:"lastStatus,omitempty"`
+	LastError    string            `json:"lastError,omitempty"`
+	Runs         int               `json:"runs"`
+	CreatedAt    time.Time         `json:"createdAt"`
+}
+
+// Lease is held by one server instance at a time until it expires, e.g.
+// to elect the instance that runs scheduled jobs
+type Lease struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AnalyticsSnapshot records the registration figures of one day, taken
+// nightly so trends can be compared after the event
+type AnalyticsSnapshot struct {
+	Date         string                 `json:"date"`
+	Attendees    int                    `json:"attendees"`
+	Designations []DesignationBreakdown `json:"designations"`
+	Sessions     int                    `json:"sessions"`
+	Speakers     int                    `json:"speakers"`
+	TakenAt      time.Time              `json:"takenAt"`
+}
+
+// Readiness statuses
+const (
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// DependencyStatus is the outcome of checking one dependency: "ok" or
+// "error"
+type DependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// BuildInfo identifies the build of the running server
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Readiness reports whether the server can take traffic, with the status
+// of every dependency it checked
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+	Build  BuildInfo                   `json:"build"`
+}
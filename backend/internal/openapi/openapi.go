@@ -0,0 +1,419 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document. Schemas
+// are generated from the models, including the validation rules of their
+// binding tags, and operations are listed next to each other in
+// operations.go, mirroring the routes registered by cmd/server.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/problem"
+)
+
+// operation is one method on one path
+type operation struct {
+	method    string
+	path      string // gin syntax, e.g. /api/sessions/:id
+	id        string
+	tag       string
+	summary   string
+	admin     bool
+	security  []Schema
+	etag      bool
+	params    []Schema
+	body      Schema
+	responses Schema
+}
+
+// option adds details to an operation
+type option func(b *builder, op *operation)
+
+type builder struct {
+	schemas    *schemas
+	operations []*operation
+}
+
+func (b *builder) add(method, path, id, tag, summary string, options ...option) {
+	op := &operation{method: method, path: path, id: id, tag: tag, summary: summary, responses: Schema{}}
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			op.params = append(op.params, Schema{
+				"name":     segment[1:],
+				"in":       "path",
+				"required": true,
+				"schema":   Schema{"type": "string"},
+			})
+		}
+	}
+	for _, o := range options {
+		o(b, op)
+	}
+	b.operations = append(b.operations, op)
+}
+
+// admin requires admin credentials
+func admin() option {
+	return func(b *builder, op *operation) {
+		op.admin = true
+		op.responses["401"] = errorRef(http.StatusUnauthorized)
+	}
+}
+
+// bearer marks an operation protected by the bearer token scheme, unless
+// the token is not configured
+func bearer(scheme string) option {
+	return func(b *builder, op *operation) {
+		op.security = []Schema{{scheme: []string{}}, {}}
+	}
+}
+
+func query(name, description string, schema Schema) option {
+	return func(b *builder, op *operation) {
+		op.params = append(op.params, Schema{"name": name, "in": "query", "description": description, "schema": schema})
+	}
+}
+
+func header(name, description string, schema Schema) option {
+	return func(b *builder, op *operation) {
+		op.params = append(op.params, Schema{"name": name, "in": "header", "description": description, "schema": schema})
+	}
+}
+
+// listQuery adds the pagination, sorting and search parameters of the
+// admin list endpoints. The first sort key, which may be descending, is
+// the default.
+func listQuery(sortKeys ...string) option {
+	return func(b *builder, op *operation) {
+		var sorts []string
+		for _, key := range sortKeys {
+			key = strings.TrimPrefix(key, "-")
+			sorts = append(sorts, key, "-"+key)
+		}
+		query("limit", "Page size", Schema{"type": "integer", "minimum": 1, "maximum": 200, "default": 50})(b, op)
+		query("cursor", "nextCursor of the previous page", Schema{"type": "string"})(b, op)
+		query("sort", "Sort key, prefixed with - for descending order", Schema{"type": "string", "enum": sorts, "default": sortKeys[0]})(b, op)
+		query("q", "Case-insensitive search text", Schema{"type": "string"})(b, op)
+	}
+}
+
+// body is a required request body of content type with v's schema
+func body(contentType string, v interface{}) option {
+	return func(b *builder, op *operation) {
+		schema := b.schemas.of(v)
+		content := Schema{contentType: Schema{"schema": schema}}
+		if contentType == "application/merge-patch+json" {
+			content["application/json"] = Schema{"schema": schema}
+		}
+		op.body = Schema{"required": true, "content": content}
+	}
+}
+
+// formBody is a required multipart/form-data body with one binary file
+// field
+func formBody(field string) option {
+	return func(b *builder, op *operation) {
+		op.body = Schema{"required": true, "content": Schema{
+			"multipart/form-data": Schema{"schema": Schema{
+				"type":       "object",
+				"properties": Schema{field: Schema{"type": "string", "format": "binary"}},
+				"required":   []string{field},
+			}},
+		}}
+	}
+}
+
+// schemaFor returns v itself if it is a Schema, or the schema of v's type
+func (b *builder) schemaFor(v interface{}) Schema {
+	if schema, ok := v.(Schema); ok {
+		return schema
+	}
+	return b.schemas.of(v)
+}
+
+// reply is a JSON response with v's schema
+func reply(status int, description string, v interface{}) option {
+	return func(b *builder, op *operation) {
+		op.responses[strconv.Itoa(status)] = Schema{
+			"description": description,
+			"content":     Schema{"application/json": Schema{"schema": b.schemaFor(v)}},
+		}
+	}
+}
+
+// alsoAs adds another content type to a response
+func alsoAs(status int, contentType string, v interface{}) option {
+	return func(b *builder, op *operation) {
+		response := op.responses[strconv.Itoa(status)].(Schema)
+		response["content"].(Schema)[contentType] = Schema{"schema": b.schemaFor(v)}
+	}
+}
+
+// replyAs is a response of any content type
+func replyAs(status int, description, contentType string, schema Schema) option {
+	return func(b *builder, op *operation) {
+		op.responses[strconv.Itoa(status)] = Schema{
+			"description": description,
+			"content":     Schema{contentType: Schema{"schema": schema}},
+		}
+	}
+}
+
+// success is a models.SuccessResponse whose data has v's schema, or no
+// data if v is nil
+func success(status int, description string, v interface{}) option {
+	return func(b *builder, op *operation) {
+		schema := b.schemas.of(models.SuccessResponse{})
+		if v != nil {
+			schema = Schema{"allOf": []interface{}{
+				schema,
+				Schema{"type": "object", "properties": Schema{"data": b.schemaFor(v)}},
+			}}
+		}
+		replyAs(status, description, "application/json", schema)(b, op)
+	}
+}
+
+// page is a models.Page of items with v's schema
+func page(v interface{}) option {
+	return func(b *builder, op *operation) {
+		schema := Schema{"allOf": []interface{}{
+			b.schemas.of(models.Page{}),
+			Schema{"type": "object", "properties": Schema{"items": Schema{"type": "array", "items": b.schemas.of(v)}}}},
+		}
+		replyAs(http.StatusOK, "A page of results", "application/json", schema)(b, op)
+	}
+}
+
+// stream is a Server-Sent Events response whose events are named event and
+// carry v as JSON
+func stream(event string, v interface{}) option {
+	return func(b *builder, op *operation) {
+		schema := Schema{"type": "string", "description": "Server-Sent Events named \"" + event + "\"; each data line is JSON"}
+		replyAs(http.StatusOK, "Event stream", "text/event-stream", schema)(b, op)
+		op.responses["200"].(Schema)["x-event-data"] = b.schemaFor(v)
+	}
+}
+
+// errs adds the standard error responses for statuses
+func errs(statuses ...int) option {
+	return func(b *builder, op *operation) {
+		for _, status := range statuses {
+			op.responses[strconv.Itoa(status)] = errorRef(status)
+		}
+	}
+}
+
+// versioned sets the ETag header on successful responses and, on writes,
+// takes an If-Match header, which PATCH requires
+func versioned() option {
+	return func(b *builder, op *operation) {
+		op.etag = true
+		switch op.method {
+		case http.MethodPut:
+			op.params = append(op.params, Schema{
+				"name": "If-Match", "in": "header",
+				"description": "ETag read earlier; the write fails with 412 if the resource changed since",
+				"schema":      Schema{"type": "string"},
+			})
+			errs(http.StatusPreconditionFailed)(b, op)
+		case http.MethodPatch:
+			op.params = append(op.params, Schema{
+				"name": "If-Match", "in": "header", "required": true,
+				"description": "ETag read earlier; the write fails with 412 if the resource changed since, and with 428 without it",
+				"schema":      Schema{"type": "string"},
+			})
+			errs(http.StatusPreconditionFailed, http.StatusPreconditionRequired)(b, op)
+		}
+	}
+}
+
+// cached is a GET whose 200 response carries an ETag validator, and
+// which answers 304 when the client's copy is current. It follows the
+// reply option.
+func cached() option {
+	return func(b *builder, op *operation) {
+		header("If-None-Match", "ETag of the copy the client has; 304 if it is current", Schema{"type": "string"})(b, op)
+		op.responses[strconv.Itoa(http.StatusOK)].(Schema)["headers"] = Schema{
+			"ETag":          Schema{"description": "Entity tag of the response, for If-None-Match", "schema": Schema{"type": "string"}},
+			"Cache-Control": Schema{"description": "How long caches may reuse the response without revalidating", "schema": Schema{"type": "string"}},
+		}
+		op.responses[strconv.Itoa(http.StatusNotModified)] = Schema{"description": "The client's copy is current"}
+	}
+}
+
+// sparse accepts the fields parameter, selecting the fields to return, and
+// an include parameter naming the related resources to embed
+func sparse(relations ...string) option {
+	return func(b *builder, op *operation) {
+		query("fields", "Comma-separated fields to return, with dots for nested fields (id,title,speakers.name)", Schema{"type": "string"})(b, op)
+		query("include", "Comma-separated related resources to embed: "+strings.Join(relations, ", "), Schema{"type": "string"})(b, op)
+		errs(http.StatusBadRequest)(b, op)
+	}
+}
+
+// idempotent accepts an Idempotency-Key header, with which retries replay
+// the first response
+func idempotent() option {
+	return func(b *builder, op *operation) {
+		header("Idempotency-Key", "Unique key of this request; retries with the same key and body replay the first response with Idempotent-Replayed: true", Schema{"type": "string", "maxLength": 255})(b, op)
+		errs(http.StatusConflict, http.StatusUnprocessableEntity)(b, op)
+	}
+}
+
+// errorResponses names the reusable error responses
+var errorResponses = map[int]string{
+	http.StatusBadRequest:            "The request is invalid",
+	http.StatusUnauthorized:          "Admin credentials are missing or invalid",
+	http.StatusForbidden:             "The action is not allowed, e.g. the attendee is not registered",
+	http.StatusNotFound:              "The resource does not exist",
+	http.StatusConflict:              "The request conflicts with the current state",
+	http.StatusPreconditionFailed:    "If-Match does not match the current version",
+	http.StatusPreconditionRequired:  "If-Match is required",
+	http.StatusRequestEntityTooLarge: "The upload is too large",
+	http.StatusUnsupportedMediaType:  "The content type is not supported",
+	http.StatusUnprocessableEntity:   "The Idempotency-Key was already used with a different request body",
+	http.StatusInternalServerError:   "Unexpected server error",
+	http.StatusServiceUnavailable:    "Storage is temporarily unavailable; retry after the Retry-After delay",
+}
+
+func errorRef(status int) Schema {
+	return Schema{"$ref": "#/components/responses/" + errorName(status)}
+}
+
+func errorName(status int) string {
+	return strings.ReplaceAll(http.StatusText(status), " ", "")
+}
+
+// Path converts a gin route path to an OpenAPI path template
+func Path(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Document builds the OpenAPI document
+func Document() Schema {
+	b := &builder{schemas: newSchemas()}
+	b.addOperations()
+	b.schemas.of(models.Problem{})
+
+	paths := Schema{}
+	for _, op := range b.operations {
+		item, ok := paths[Path(op.path)].(Schema)
+		if !ok {
+			item = Schema{}
+			paths[Path(op.path)] = item
+		}
+
+		// anything that can fail with 500 reads storage, which may be down
+		if _, ok := op.responses["500"]; ok {
+			op.responses["503"] = errorRef(http.StatusServiceUnavailable)
+		}
+
+		object := Schema{
+			"operationId": op.id,
+			"tags":        []string{op.tag},
+			"summary":     op.summary,
+			"responses":   op.responses,
+		}
+		if len(op.params) > 0 {
+			object["parameters"] = op.params
+		}
+		if op.body != nil {
+			object["requestBody"] = op.body
+		}
+		if op.admin {
+			object["security"] = []Schema{
+				{"adminPassword": []string{}},
+				{"adminEmail": []string{}, "adminPassword": []string{}},
+			}
+		}
+		if op.security != nil {
+			object["security"] = op.security
+		}
+		if op.etag {
+			for status, response := range op.responses {
+				if strings.HasPrefix(status, "2") {
+					response.(Schema)["headers"] = Schema{"ETag": Schema{
+						"description": "Version of the resource, for If-Match",
+						"schema":      Schema{"type": "string"},
+					}}
+				}
+			}
+		}
+		item[strings.ToLower(op.method)] = object
+	}
+
+	responses := Schema{}
+	for status, description := range errorResponses {
+		responses[errorName(status)] = Schema{
+			"description": description,
+			"content":     Schema{problem.ContentType: Schema{"schema": ref("Problem")}},
+		}
+	}
+
+	return Schema{
+		"openapi": "3.0.3",
+		"info": Schema{
+			"title":       "Workshop API",
+			"version":     "1.0.0",
+			"description": "Event registration, sessions, speakers, live Q&A and polls, feedback and the admin dashboard.",
+		},
+		"servers": []Schema{{"url": "/"}},
+		"tags":    tags,
+		"paths":   paths,
+		"components": Schema{
+			"schemas":   b.schemas.components,
+			"responses": responses,
+			"securitySchemes": Schema{
+				"adminPassword": Schema{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-Admin-Password",
+					"description": "ADMIN_PASSWORD, or the password of the admin user named in X-Admin-Email",
+				},
+				"adminEmail": Schema{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "X-Admin-Email",
+					"description": "Email of an admin user created with workshopctl",
+				},
+				"metricsToken": Schema{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "METRICS_TOKEN, when set",
+				},
+			},
+		},
+	}
+}
+
+var (
+	encodeOnce sync.Once
+	encoded    []byte
+	encodeErr  error
+)
+
+// JSON returns the encoded OpenAPI document
+func JSON() ([]byte, error) {
+	encodeOnce.Do(func() {
+		encoded, encodeErr = json.MarshalIndent(Document(), "", "  ")
+	})
+	return encoded, encodeErr
+}
+
+// DocsPage is a self-contained HTML viewer for the document, which it loads
+// from openapi.json next to it
+//
+//go:embed docs.html
+var DocsPage []byte
@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"net/http"
+
+	"appdirect-workshop-backend/internal/models"
+)
+
+var tags = []Schema{
+	{"name": "Health", "description": "Service status"},
+	{"name": "Docs", "description": "This document and its viewer"},
+	{"name": "Sessions", "description": "Workshop sessions"},
+	{"name": "Speakers", "description": "Speakers and their photos"},
+	{"name": "Attendees", "description": "Registration and attendee management"},
+	{"name": "Questions", "description": "Live Q&A during sessions"},
+	{"name": "Polls", "description": "Live polls during sessions"},
+	{"name": "Feedback", "description": "Session ratings and comments"},
+	{"name": "Media", "description": "Uploaded media files"},
+	{"name": "Analytics", "description": "Registration and poll analytics"},
+	{"name": "Trash", "description": "Soft-deleted attendees, speakers and sessions"},
+	{"name": "Backup", "description": "Event export and restore"},
+	{"name": "Webhooks", "description": "Signed event notifications to external URLs"},
+	{"name": "Jobs", "description": "Scheduled background jobs"},
+}
+
+// addOperations lists every route registered by cmd/server
+func (b *builder) addOperations() {
+	const (
+		get   = http.MethodGet
+		post  = http.MethodPost
+		put   = http.MethodPut
+		patch = http.MethodPatch
+		del   = http.MethodDelete
+	)
+	dateRange := func(what string) []option {
+		return []option{
+			query("from", "Earliest "+what+" (RFC 3339 or YYYY-MM-DD)", Schema{"type": "string"}),
+			query("to", "Latest "+what+" (RFC 3339 or YYYY-MM-DD, inclusive)", Schema{"type": "string"}),
+		}
+	}
+	count := Schema{"type": "object", "properties": Schema{"count": Schema{"type": "integer"}}, "required": []string{"count"}}
+
+	// Health and docs
+	b.add(get, "/health", "health", "Health", "Report that the server is up",
+		replyAs(http.StatusOK, "The server is up", "application/json",
+			Schema{"type": "object", "properties": Schema{"status": Schema{"type": "string", "enum": []string{"ok"}}}}))
+	b.add(get, "/livez", "livez", "Health", "Report that the process is up (liveness probe)",
+		replyAs(http.StatusOK, "The process is up", "application/json",
+			Schema{"type": "object", "properties": Schema{"status": Schema{"type": "string", "enum": []string{"ok"}}}}))
+	b.add(get, "/readyz", "readyz", "Health", "Check storage and report whether the server can take traffic (readiness probe)",
+		reply(http.StatusOK, "Every dependency is reachable", models.Readiness{}),
+		reply(http.StatusServiceUnavailable, "A dependency is unreachable or the server is shutting down", models.Readiness{}))
+	b.add(get, "/metrics", "metrics", "Health", "Prometheus metrics",
+		bearer("metricsToken"),
+		replyAs(http.StatusOK, "Metrics in the Prometheus text format", "text/plain", Schema{"type": "string"}))
+	b.add(get, "/api/openapi.json", "getOpenAPI", "Docs", "Get this OpenAPI document",
+		replyAs(http.StatusOK, "OpenAPI 3 document", "application/json", Schema{"type": "object"}))
+	b.add(get, "/api/docs", "getDocs", "Docs", "Browse this OpenAPI document",
+		replyAs(http.StatusOK, "HTML documentation viewer", "text/html", Schema{"type": "string"}))
+
+	// Sessions
+	b.add(get, "/api/sessions", "getSessions", "Sessions", "List sessions with their speakers",
+		reply(http.StatusOK, "Every session with its speakers", []models.SessionWithSpeakers{}), cached(), sparse("speakers"), errs(500))
+	b.add(get, "/api/sessions/:id", "getSession", "Sessions", "Get a session",
+		reply(http.StatusOK, "The session, with a speakers array if include=speakers or fields selects speakers", models.Session{}), versioned(), sparse("speakers"), errs(404))
+	b.add(post, "/api/sessions/:id/feedback", "submitFeedback", "Feedback", "Rate a session that has ended (once per attendee)",
+		body("application/json", models.FeedbackSubmission{}), idempotent(),
+		success(http.StatusCreated, "Feedback stored", models.Feedback{}), errs(400, 403, 404, 409, 500))
+
+	// Live Q&A
+	b.add(get, "/api/sessions/:id/questions", "getQuestions", "Questions", "List the visible questions of a session, pinned first",
+		reply(http.StatusOK, "Visible questions", []models.Question{}), errs(404, 500))
+	b.add(get, "/api/sessions/:id/questions/stream", "streamQuestions", "Questions", "Stream the visible questions of a session",
+		stream("questions", []models.Question{}), errs(404, 500))
+	b.add(post, "/api/sessions/:id/questions", "askQuestion", "Questions", "Ask a question as a registered attendee",
+		body("application/json", models.QuestionSubmission{}), idempotent(),
+		success(http.StatusCreated, "Question stored", models.Question{}), errs(400, 403, 404, 500))
+	b.add(post, "/api/sessions/:id/questions/:questionId/upvote", "upvoteQuestion", "Questions", "Upvote a question (once per attendee)",
+		body("application/json", models.QuestionUpvote{}),
+		success(http.StatusOK, "Upvote counted", models.Question{}), errs(400, 404, 409, 500))
+
+	// Live polls
+	b.add(get, "/api/sessions/:id/polls", "getPolls", "Polls", "List the open and closed polls of a session",
+		reply(http.StatusOK, "Open and closed polls", []models.Poll{}), errs(404, 500))
+	b.add(get, "/api/sessions/:id/polls/stream", "streamPolls", "Polls", "Stream the open and closed polls of a session",
+		stream("polls", []models.Poll{}), errs(404, 500))
+	b.add(post, "/api/sessions/:id/polls/:pollId/votes", "votePoll", "Polls", "Vote in an open poll (once per attendee)",
+		body("application/json", models.PollVote{}), idempotent(),
+		success(http.StatusOK, "Vote recorded", nil), errs(400, 404, 409, 500))
+
+	// Speakers
+	b.add(get, "/api/speakers", "getSpeakers", "Speakers", "List speakers",
+		reply(http.StatusOK, "Every speaker; include=sessions replaces session IDs with the sessions", []models.Speaker{}), cached(), sparse("sessions"), errs(500))
+	b.add(get, "/api/speakers/:id", "getSpeaker", "Speakers", "Get a speaker",
+		reply(http.StatusOK, "The speaker; include=sessions replaces session IDs with the sessions", models.Speaker{}), versioned(), sparse("sessions"), errs(404))
+
+	// Media
+	b.add(get, "/api/media/*key", "getMedia", "Media", "Download an uploaded file (cached for a year)",
+		replyAs(http.StatusOK, "The file", "application/octet-stream", Schema{"type": "string", "format": "binary"}), errs(404, 500))
+
+	// Attendees
+	b.add(get, "/api/attendees/count", "getAttendeeCount", "Attendees", "Count registered attendees",
+		replyAs(http.StatusOK, "Number of attendees", "application/json", count), errs(500))
+	b.add(get, "/api/attendees/count/stream", "streamAttendeeCount", "Attendees", "Stream the attendee count and seats left per session",
+		stream("stats", models.RegistrationStats{}), errs(500))
+	b.add(post, "/api/attendees", "registerAttendee", "Attendees", "Register for the workshop",
+		body("application/json", models.Attendee{}), idempotent(),
+		success(http.StatusCreated, "Registered", models.Attendee{}), errs(400, 500))
+
+	// Admin: attendees
+	b.add(get, "/api/admin/attendees", "listAttendees", "Attendees", "List attendees",
+		append([]option{admin(), listQuery("registeredAt", "name", "email", "designation"),
+			query("designation", "Only this designation", Schema{"type": "string"}),
+			page(models.Attendee{}), errs(400, 500)}, dateRange("registration date")...)...)
+	b.add(get, "/api/admin/attendees/:id", "getAttendee", "Attendees", "Get an attendee",
+		admin(), reply(http.StatusOK, "The attendee", models.Attendee{}), errs(404))
+	b.add(del, "/api/admin/attendees/:id", "deleteAttendee", "Attendees", "Move an attendee to the trash",
+		admin(), success(http.StatusOK, "Moved to the trash", nil), errs(404, 500))
+
+	// Admin: speakers
+	b.add(get, "/api/admin/speakers", "listSpeakers", "Speakers", "List speakers",
+		admin(), listQuery("name"), query("session", "Only speakers of this session", Schema{"type": "string"}),
+		page(models.Speaker{}), errs(400, 500))
+	b.add(post, "/api/admin/speakers", "createSpeaker", "Speakers", "Create a speaker",
+		admin(), body("application/json", models.Speaker{}), idempotent(),
+		success(http.StatusCreated, "Created", models.Speaker{}), versioned(), errs(400, 500))
+	b.add(put, "/api/admin/speakers/:id", "updateSpeaker", "Speakers", "Replace a speaker",
+		admin(), body("application/json", models.Speaker{}),
+		success(http.StatusOK, "Updated", models.Speaker{}), versioned(), errs(400, 404, 500))
+	b.add(patch, "/api/admin/speakers/:id", "patchSpeaker", "Speakers", "Update a speaker with a JSON Merge Patch (RFC 7386)",
+		admin(), body("application/merge-patch+json", models.Speaker{}),
+		success(http.StatusOK, "Updated", models.Speaker{}), versioned(), errs(400, 404, 415, 500))
+	b.add(del, "/api/admin/speakers/:id", "deleteSpeaker", "Speakers", "Move a speaker to the trash",
+		admin(), success(http.StatusOK, "Moved to the trash", nil), errs(404, 500))
+	b.add(post, "/api/admin/speakers/:id/photo", "uploadSpeakerPhoto", "Speakers", "Upload a speaker photo (JPEG, PNG, GIF or WebP)",
+		admin(), formBody("photo"),
+		success(http.StatusOK, "Photo stored", models.Speaker{}), versioned(), errs(400, 404, 413, 415, 500))
+	b.add(del, "/api/admin/speakers/:id/photo", "deleteSpeakerPhoto", "Speakers", "Remove the uploaded speaker photo",
+		admin(), success(http.StatusOK, "Photo removed", nil), errs(404, 500))
+
+	// Admin: sessions
+	b.add(get, "/api/admin/sessions", "listSessions", "Sessions", "List sessions with their speakers",
+		append([]option{admin(), listQuery("startsAt", "title"),
+			query("speaker", "Only sessions of this speaker", Schema{"type": "string"}),
+			page(models.SessionWithSpeakers{}), errs(400, 500)}, dateRange("start time")...)...)
+	b.add(post, "/api/admin/sessions", "createSession", "Sessions", "Create a session",
+		admin(), body("application/json", models.Session{}), idempotent(),
+		success(http.StatusCreated, "Created", models.Session{}), versioned(), errs(400, 500))
+	b.add(put, "/api/admin/sessions/:id", "updateSession", "Sessions", "Replace a session",
+		admin(), body("application/json", models.Session{}),
+		success(http.StatusOK, "Updated", models.Session{}), versioned(), errs(400, 404, 500))
+	b.add(patch, "/api/admin/sessions/:id", "patchSession", "Sessions", "Update a session with a JSON Merge Patch (RFC 7386)",
+		admin(), body("application/merge-patch+json", models.Session{}),
+		success(http.StatusOK, "Updated", models.Session{}), versioned(), errs(400, 404, 415, 500))
+	b.add(del, "/api/admin/sessions/:id", "deleteSession", "Sessions", "Move a session to the trash",
+		admin(), success(http.StatusOK, "Moved to the trash", nil), errs(404, 500))
+	b.add(get, "/api/admin/sessions/:id/questions", "getAllQuestions", "Questions", "List every question of a session, including hidden ones",
+		admin(), reply(http.StatusOK, "Every question", []models.Question{}), errs(500))
+	b.add(patch, "/api/admin/sessions/:id/questions/:questionId", "moderateQuestion", "Questions", "Hide, answer or pin a question",
+		admin(), body("application/json", models.QuestionModeration{}),
+		success(http.StatusOK, "Updated", models.Question{}), errs(400, 404, 500))
+	b.add(get, "/api/admin/sessions/:id/polls", "getAllPolls", "Polls", "List every poll of a session, including drafts",
+		admin(), reply(http.StatusOK, "Every poll", []models.Poll{}), errs(500))
+	b.add(post, "/api/admin/sessions/:id/polls", "createPoll", "Polls", "Create a draft poll",
+		admin(), body("application/json", models.PollInput{}), idempotent(),
+		success(http.StatusCreated, "Created", models.Poll{}), errs(400, 404, 500))
+	b.add(post, "/api/admin/sessions/:id/polls/:pollId/open", "openPoll", "Polls", "Open a poll for voting",
+		admin(), success(http.StatusOK, "Opened", models.Poll{}), errs(404, 409, 500))
+	b.add(post, "/api/admin/sessions/:id/polls/:pollId/close", "closePoll", "Polls", "Close an open poll",
+		admin(), success(http.StatusOK, "Closed", models.Poll{}), errs(404, 409, 500))
+	b.add(del, "/api/admin/sessions/:id/polls/:pollId", "deletePoll", "Polls", "Delete a draft poll",
+		admin(), success(http.StatusOK, "Deleted", nil), errs(404, 409, 500))
+
+	// Admin: analytics
+	b.add(get, "/api/admin/analytics/designation", "getDesignationBreakdown", "Analytics", "Count attendees per designation",
+		admin(), reply(http.StatusOK, "Attendees per designation", []models.DesignationBreakdown{}), errs(500))
+	b.add(get, "/api/admin/analytics/polls", "getPollResults", "Analytics", "Poll results grouped by session",
+		admin(), reply(http.StatusOK, "Poll results", []models.SessionPollResults{}), errs(500))
+	b.add(post, "/api/admin/analytics/recompute", "recomputeCounters", "Analytics", "Rebuild the attendee counters from the attendee records",
+		admin(), success(http.StatusOK, "Counters rebuilt", count), errs(500))
+	b.add(get, "/api/admin/analytics/snapshots", "getAnalyticsSnapshots", "Analytics", "Nightly registration snapshots, oldest first",
+		admin(), reply(http.StatusOK, "Snapshots", []models.AnalyticsSnapshot{}), errs(500))
+
+	// Admin: trash
+	kind := Schema{"type": "string", "enum": []string{"attendees", "speakers", "sessions"}}
+	b.add(get, "/api/admin/trash/:kind", "getTrash", "Trash", "List trashed items, most recently deleted first",
+		admin(), pathSchema("kind", kind), reply(http.StatusOK, "Trashed items", []models.TrashItem{}), errs(404, 500))
+	b.add(post, "/api/admin/trash/:kind/:id/restore", "restoreFromTrash", "Trash", "Restore a trashed item",
+		admin(), pathSchema("kind", kind), success(http.StatusOK, "Restored", Schema{}), errs(404, 409, 500))
+	b.add(del, "/api/admin/trash/:kind/:id", "purgeFromTrash", "Trash", "Delete a trashed item permanently",
+		admin(), pathSchema("kind", kind), success(http.StatusOK, "Deleted", nil), errs(404, 500))
+
+	// Admin: backup
+	b.add(get, "/api/admin/backup", "exportEvent", "Backup", "Download the whole event as a JSON archive",
+		admin(), reply(http.StatusOK, "Event archive (sent as an attachment)", models.EventArchive{}), errs(500))
+	b.add(post, "/api/admin/restore", "restoreEvent", "Backup", "Restore a JSON archive into this or another event",
+		admin(),
+		query("event", "Target event ID (default: this event)", Schema{"type": "string"}),
+		query("onConflict", "What to do with documents that already exist", Schema{"type": "string", "enum": []string{models.ConflictFail, models.ConflictSkip, models.ConflictOverwrite}, "default": models.ConflictFail}),
+		query("dryRun", "Only report what would change", Schema{"type": "boolean", "default": false}),
+		body("application/json", models.EventArchive{}),
+		success(http.StatusOK, "Restore report", models.RestoreReport{}), errs(400, 409, 500))
+
+	// Admin: feedback
+	b.add(get, "/api/admin/feedback/sessions", "getSessionFeedback", "Feedback", "Feedback summary per session",
+		admin(), reply(http.StatusOK, "Summaries", []models.FeedbackSummary{}), errs(500))
+	b.add(get, "/api/admin/feedback/sessions/:id", "getSessionFeedbackSummary", "Feedback", "Feedback summary and comments for a session",
+		admin(), reply(http.StatusOK, "Summary", models.FeedbackSummary{}), errs(404, 500))
+	b.add(get, "/api/admin/feedback/speakers", "getSpeakerFeedback", "Feedback", "Feedback summary per speaker",
+		admin(), reply(http.StatusOK, "Summaries", []models.FeedbackSummary{}), errs(500))
+	b.add(get, "/api/admin/feedback/speakers/:id", "getSpeakerFeedbackSummary", "Feedback", "Feedback summary and comments for a speaker",
+		admin(), reply(http.StatusOK, "Summary", models.FeedbackSummary{}), errs(404, 500))
+	b.add(get, "/api/admin/feedback/export", "exportFeedback", "Feedback", "Download all feedback as CSV, or JSON with format=json",
+		admin(), query("format", "File format", Schema{"type": "string", "enum": []string{"csv", "json"}, "default": "csv"}),
+		replyAs(http.StatusOK, "All feedback (sent as an attachment)", "text/csv", Schema{"type": "string"}),
+		alsoAs(http.StatusOK, "application/json", []models.Feedback{}), errs(500))
+
+	// Admin: webhooks
+	b.add(get, "/api/admin/webhooks", "listWebhooks", "Webhooks", "List webhooks (without their secrets)",
+		admin(), reply(http.StatusOK, "Webhooks", []models.Webhook{}), errs(500))
+	b.add(post, "/api/admin/webhooks", "createWebhook", "Webhooks", "Subscribe a URL to events; the response is the only one that includes the signing secret",
+		admin(), body("application/json", models.WebhookInput{}), idempotent(),
+		success(http.StatusCreated, "Created", models.Webhook{}), errs(400, 500))
+	b.add(get, "/api/admin/webhooks/:id", "getWebhook", "Webhooks", "Get a webhook (without its secret)",
+		admin(), reply(http.StatusOK, "Webhook", models.Webhook{}), errs(404, 500))
+	b.add(put, "/api/admin/webhooks/:id", "updateWebhook", "Webhooks", "Change the URL, events and description of a webhook",
+		admin(), body("application/json", models.WebhookInput{}),
+		success(http.StatusOK, "Updated", models.Webhook{}), errs(400, 404, 500))
+	b.add(del, "/api/admin/webhooks/:id", "deleteWebhook", "Webhooks", "Delete a webhook, keeping its delivery log",
+		admin(), success(http.StatusOK, "Deleted", nil), errs(404, 500))
+	b.add(post, "/api/admin/webhooks/:id/enable", "enableWebhook", "Webhooks", "Resume deliveries and clear the failure count",
+		admin(), success(http.StatusOK, "Enabled", models.Webhook{}), errs(404, 500))
+	b.add(post, "/api/admin/webhooks/:id/disable", "disableWebhook", "Webhooks", "Stop deliveries",
+		admin(), success(http.StatusOK, "Disabled", models.Webhook{}), errs(404, 500))
+	b.add(get, "/api/admin/webhooks/:id/deliveries", "listWebhookDeliveries", "Webhooks", "Page through the delivery log of a webhook, newest first",
+		admin(), listQuery("-createdAt"),
+		query("status", "Only deliveries with this status", Schema{"type": "string", "enum": []string{models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed}}),
+		query("event", "Only deliveries of this event type", Schema{"type": "string"}),
+		page(models.WebhookDelivery{}), errs(400, 500))
+	b.add(post, "/api/admin/webhooks/:id/deliveries/:deliveryId/replay", "replayWebhookDelivery", "Webhooks", "Send a logged event again as a new delivery",
+		admin(), success(http.StatusAccepted, "Queued", models.WebhookDelivery{}), errs(404, 409, 500))
+
+	// Admin: scheduled jobs
+	b.add(get, "/api/admin/jobs", "listJobs", "Jobs", "List scheduled jobs with their next and last run",
+		admin(), reply(http.StatusOK, "Jobs", []models.Job{}), errs(500))
+	b.add(post, "/api/admin/jobs/:name/run", "runJob", "Jobs", "Run a job now",
+		admin(), success(http.StatusAccepted, "Queued", models.Job{}), errs(404, 500))
+}
+
+// pathSchema narrows the schema of a path parameter
+func pathSchema(name string, schema Schema) option {
+	return func(b *builder, op *operation) {
+		for _, param := range op.params {
+			if param["in"] == "path" && param["name"] == name {
+				param["schema"] = schema
+			}
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema object as used by OpenAPI 3.0
+type Schema = map[string]interface{}
+
+// readOnly lists JSON properties that the server sets and ignores in
+// request bodies
+var readOnly = map[string]bool{
+	"id":           true,
+	"version":      true,
+	"registeredAt": true,
+	"photo":        true,
+	"createdAt":    true,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas turns Go types into schemas. Named struct types become
+// components referenced by name, with the validation rules of their
+// binding tags.
+type schemas struct {
+	components map[string]Schema
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]Schema{}}
+}
+
+// of returns the schema of v's type
+func (s *schemas) of(v interface{}) Schema {
+	return s.schemaOf(reflect.TypeOf(v))
+}
+
+func (s *schemas) schemaOf(t reflect.Type) Schema {
+	if t == nil {
+		return Schema{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		if _, ok := s.components[t.Name()]; !ok {
+			s.components[t.Name()] = nil // guards against recursive types
+			s.components[t.Name()] = s.structSchema(t)
+		}
+		return ref(t.Name())
+	case reflect.Slice:
+		return Schema{"type": "array", "items": s.schemaOf(t.Elem())}
+	case reflect.Array:
+		return Schema{"type": "array", "items": s.schemaOf(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return Schema{"type": "object", "additionalProperties": true}
+		}
+		return Schema{"type": "object", "additionalProperties": s.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return Schema{}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	}
+	return Schema{}
+}
+
+// structSchema describes the JSON encoding of a struct, flattening embedded
+// structs like encoding/json does
+func (s *schemas) structSchema(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	s.addFields(t, properties, &required)
+
+	schema := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s *schemas) addFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(field.Type, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := s.schemaOf(field.Type)
+		if readOnly[name] {
+			schema = withReadOnly(schema)
+		}
+		if applyBinding(schema, field.Tag.Get("binding")) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// applyBinding adds the validation rules of a go-playground/validator tag
+// to schema and reports whether the field is required. Rules after "dive"
+// apply to the items of a list.
+func applyBinding(schema Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	required, dived := false, false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			items, ok := target["items"].(Schema)
+			if !ok {
+				return required
+			}
+			target, dived = items, true
+		case "required":
+			if target["type"] == "string" {
+				if _, ok := target["minLength"]; !ok {
+					target["minLength"] = 1
+				}
+			}
+			required = required || !dived
+		case "email":
+			target["format"] = "email"
+		case "url", "http_url":
+			target["format"] = "uri"
+		case "oneof":
+			target["enum"] = strings.Fields(param)
+		case "min", "gte":
+			setBound(target, param, "minLength", "minimum", "minItems")
+		case "max", "lte":
+			setBound(target, param, "maxLength", "maximum", "maxItems")
+		}
+	}
+	return required
+}
+
+// setBound sets the string, number or array bound matching the schema type
+func setBound(schema Schema, param, stringKey, numberKey, arrayKey string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema["type"] {
+	case "string":
+		schema[stringKey] = int(n)
+	case "integer":
+		schema[numberKey] = int(n)
+	case "number":
+		schema[numberKey] = n
+	case "array":
+		schema[arrayKey] = int(n)
+	}
+}
+
+func withReadOnly(schema Schema) Schema {
+	if _, isRef := schema["$ref"]; isRef {
+		// Siblings of $ref are ignored in OpenAPI 3.0
+		return Schema{"allOf": []interface{}{schema}, "readOnly": true}
+	}
+	schema["readOnly"] = true
+	return schema
+}
+
+func ref(name string) Schema {
+	return Schema{"$ref": "#/components/schemas/" + name}
+}
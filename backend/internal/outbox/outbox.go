@@ -0,0 +1,151 @@
+// Package outbox hands domain events written to the Firestore outbox to
+// their handlers. Events are claimed under a lease, so each is handled by
+// one instance at a time, and removed only once every handler succeeded:
+// handlers run at least once per event and must tolerate repeats.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"appdirect-workshop-backend/internal/events"
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+)
+
+const (
+	// lease is how long a claimed event is hidden from other instances; an
+	// event still leased after a crash is claimed again once it lapses
+	lease = time.Minute
+
+	// pollInterval is how often the outbox is checked when nothing on
+	// this instance signals a change, and claimBatch how many events are
+	// claimed at a time. The batch is handled in turn under one lease, so
+	// it is kept small.
+	pollInterval = 5 * time.Second
+	claimBatch   = 5
+
+	// retryBase is the delay before a failed event is retried; it grows
+	// with each attempt up to retryMax
+	retryBase = 10 * time.Second
+	retryMax  = 10 * time.Minute
+
+	// maxAttempts is the number of attempts before an event is moved to
+	// the dead letters, about an hour and a half after it was written
+	maxAttempts = 15
+)
+
+// wakeTopics are the bus topics published after changes that write to the
+// outbox, so this instance handles its own events without waiting to poll
+var wakeTopics = []string{events.TopicRegistrations, events.TopicSpeakers}
+
+// Handler handles one event. It may be called again for the same event,
+// e.g. after another handler failed or the instance stopped midway.
+type Handler func(ctx context.Context, event *models.OutboxEvent) error
+
+// Dispatcher claims outbox events and runs the handlers on them
+type Dispatcher struct {
+	repo     *repository.Repository
+	bus      *events.Bus
+	handlers []Handler
+}
+
+func NewDispatcher(repo *repository.Repository, bus *events.Bus) *Dispatcher {
+	return &Dispatcher{repo: repo, bus: bus}
+}
+
+// Handle adds a handler for every event. Handlers are added before Run.
+func (d *Dispatcher) Handle(h Handler) {
+	d.handlers = append(d.handlers, h)
+}
+
+// Run dispatches outbox events until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	for _, topic := range wakeTopics {
+		sub := d.bus.Subscribe(topic)
+		defer sub.Close()
+		go func() {
+			for range sub.Events() {
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.dispatchDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+// dispatchDue handles the claimable events, a batch at a time
+func (d *Dispatcher) dispatchDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		claimed, err := d.repo.ClaimOutboxEvents(ctx, claimBatch, lease)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to claim outbox events", "error", err)
+			return
+		}
+		for i := range claimed {
+			// Leave events whose lease lapsed while earlier ones were
+			// handled to whichever instance claims them next
+			if time.Now().After(claimed[i].LeasedUntil) {
+				break
+			}
+			d.dispatch(ctx, &claimed[i])
+		}
+		if len(claimed) < claimBatch {
+			return
+		}
+	}
+}
+
+// dispatch runs every handler on event, then removes it, or schedules a
+// retry if a handler failed. After maxAttempts it gives up and moves the
+// event to the dead letters.
+func (d *Dispatcher) dispatch(ctx context.Context, event *models.OutboxEvent) {
+	logger := logging.FromContext(ctx).With("eventId", event.ID, "eventType", event.Type)
+	ctx = logging.WithLogger(ctx, logger)
+	for _, h := range d.handlers {
+		if err := h(ctx, event); err != nil {
+			if event.Attempts >= maxAttempts {
+				logger.Error("Giving up on outbox event", "attempts", event.Attempts, "error", err)
+				if err := d.repo.DeadLetterOutboxEvent(ctx, event.ID, err.Error()); err != nil {
+					logger.Error("Failed to dead-letter outbox event", "error", err)
+				}
+				return
+			}
+			logger.Warn("Failed to handle outbox event", "attempt", event.Attempts, "error", err)
+			retryAt := time.Now().Add(backoff(event.Attempts))
+			if err := d.repo.RetryOutboxEvent(ctx, event.ID, retryAt, err.Error()); err != nil {
+				logger.Error("Failed to reschedule outbox event", "error", err)
+			}
+			return
+		}
+	}
+
+	if err := d.repo.CompleteOutboxEvent(ctx, event.ID); err != nil {
+		// The lease lapses and the event is handled again
+		logger.Error("Failed to complete outbox event", "error", err)
+	}
+}
+
+// backoff returns the delay after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
@@ -0,0 +1,92 @@
+// Package problem writes error responses as RFC 7807 problem details
+// (application/problem+json) with stable machine-readable codes. Domain
+// errors from the repository are translated here; the text of unexpected
+// errors is logged and never sent to clients.
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// Stable error codes. Clients may rely on these; the detail text may
+// change.
+const (
+	CodeInvalidRequest        = "invalid_request"
+	CodeValidation            = "validation_failed"
+	CodeUnauthorized          = "unauthorized"
+	CodeForbidden             = "forbidden"
+	CodeNotFound              = "not_found"
+	CodeConflict              = "conflict"
+	CodeAlreadyExists         = "already_exists"
+	CodeVersionMismatch       = "version_mismatch"
+	CodePreconditionRequired  = "precondition_required"
+	CodePayloadTooLarge       = "payload_too_large"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodeIdempotencyMismatch   = "idempotency_key_mismatch"
+	CodeIdempotencyInProgress = "idempotency_key_in_use"
+	CodeUnavailable           = "unavailable"
+	CodeInternal              = "internal_error"
+)
+
+// Write sends a problem response and aborts the request
+func Write(c *gin.Context, status int, code, detail string, fields ...models.FieldError) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(status, models.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   fields,
+	})
+}
+
+// Rejected sends the generic 400 for a request that is invalid for a
+// reason the client should not learn, e.g. that an email is not registered
+func Rejected(c *gin.Context) {
+	Write(c, http.StatusBadRequest, CodeValidation, "The request was rejected as invalid")
+}
+
+// Error translates err into a problem response with generic details
+func Error(c *gin.Context, err error) {
+	ErrorFor(c, "", err)
+}
+
+// ErrorFor translates err into a problem response whose not found and
+// already exists details name subject, e.g. "Session not found"
+func ErrorFor(c *gin.Context, subject string, err error) {
+	if subject == "" {
+		subject = "Resource"
+	}
+
+	switch {
+	case errors.Is(err, repository.ErrVersionConflict):
+		Write(c, http.StatusPreconditionFailed, CodeVersionMismatch, "The resource has changed; reload it and try again")
+	case errors.Is(err, repository.ErrAlreadyExists):
+		Write(c, http.StatusConflict, CodeAlreadyExists, subject+" already exists")
+	case errors.Is(err, repository.ErrNotFound):
+		Write(c, http.StatusNotFound, CodeNotFound, subject+" not found")
+	case errors.Is(err, repository.ErrConflict):
+		Write(c, http.StatusConflict, CodeConflict, "The request conflicts with the current state")
+	case errors.Is(err, repository.ErrValidation):
+		Rejected(c)
+	case errors.Is(err, repository.ErrUnavailable):
+		logging.FromContext(c.Request.Context()).Error("Request failed", "error", err)
+		c.Header("Retry-After", "5")
+		Write(c, http.StatusServiceUnavailable, CodeUnavailable, "Storage is temporarily unavailable; try again shortly")
+	default:
+		logging.FromContext(c.Request.Context()).Error("Request failed", "error", err)
+		Write(c, http.StatusInternalServerError, CodeInternal, "An unexpected error occurred")
+	}
+}
@@ -0,0 +1,138 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report binding tag failures with the JSON names of the fields
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// fieldError is an error about one request field or query parameter
+type fieldError struct {
+	models.FieldError
+}
+
+func (e *fieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// InvalidField returns an error about one request field or query
+// parameter, which Bind reports as a validation problem
+func InvalidField(field, rule, message string) error {
+	return &fieldError{models.FieldError{Field: field, Rule: rule, Message: message}}
+}
+
+// Bind writes the problem response for an error from binding or
+// validating a request body or query parameters: 400 with per-field
+// details, or 413 if the body was too large
+func Bind(c *gin.Context, err error) {
+	var invalid validator.ValidationErrors
+	var field *fieldError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &field):
+		Write(c, http.StatusBadRequest, CodeValidation, "The request has invalid fields", field.FieldError)
+	case errors.As(err, &invalid):
+		fields := make([]models.FieldError, 0, len(invalid))
+		for _, fe := range invalid {
+			fields = append(fields, models.FieldError{Field: fieldPath(fe), Rule: fe.Tag(), Message: fieldMessage(fe)})
+		}
+		Write(c, http.StatusBadRequest, CodeValidation, "The request has invalid fields", fields...)
+	case errors.As(err, &typeErr):
+		field := models.FieldError{Field: typeErr.Field, Rule: "type", Message: "must be " + jsonType(typeErr.Type)}
+		Write(c, http.StatusBadRequest, CodeValidation, "The request has invalid fields", field)
+	case errors.As(err, &tooLarge):
+		Write(c, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "The request body is too large")
+	case errors.Is(err, io.EOF):
+		Write(c, http.StatusBadRequest, CodeInvalidRequest, "A JSON request body is required")
+	default:
+		Write(c, http.StatusBadRequest, CodeInvalidRequest, "The request body is not valid JSON")
+	}
+}
+
+// Invalid writes a 400 validation problem for a single field
+func Invalid(c *gin.Context, field, rule, message string) {
+	Write(c, http.StatusBadRequest, CodeValidation, "The request has invalid fields",
+		models.FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// fieldPath is the JSON path of a field, without the name of the
+// top-level struct, e.g. "options[1]"
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "http_url":
+		return "must be an http or https URL"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte":
+		return bound(fe, "at least")
+	case "max", "lte":
+		return bound(fe, "at most")
+	}
+	return "is invalid"
+}
+
+func bound(fe validator.FieldError, comparison string) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return "must be " + comparison + " " + fe.Param() + " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "must have " + comparison + " " + fe.Param() + " items"
+	}
+	return "must be " + comparison + " " + fe.Param()
+}
+
+// jsonType names a Go type the way JSON does
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Admin users live in the top-level admins collection, keyed by lower-case
+// email, so they are not part of event archives or clones
+
+func adminID(email string) string {
+	return normalizeEmail(email)
+}
+
+// CreateAdminUser stores an admin user with a bcrypt hash of password. It
+// returns ErrAlreadyExists if the email is taken.
+func (r *Repository) CreateAdminUser(ctx context.Context, admin *models.AdminUser, password string) (err error) {
+	ctx, op := r.begin(ctx, "CreateAdminUser")
+	defer op.end(&err)
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	admin.Email = adminID(admin.Email)
+	admin.PasswordHash = string(hash)
+	admin.CreatedAt = time.Now()
+
+	_, err = r.adminsColl.Doc(admin.Email).Create(ctx, admin)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetAdminUsers returns every admin user ordered by email
+func (r *Repository) GetAdminUsers(ctx context.Context) (_ []models.AdminUser, err error) {
+	ctx, op := r.begin(ctx, "GetAdminUsers")
+	defer op.end(&err)
+	docs, err := r.adminsColl.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	admins := make([]models.AdminUser, 0, len(docs))
+	for _, doc := range docs {
+		var admin models.AdminUser
+		if err := doc.DataTo(&admin); err != nil {
+			continue
+		}
+		admins = append(admins, admin)
+	}
+	sort.Slice(admins, func(i, j int) bool { return admins[i].Email < admins[j].Email })
+	return admins, nil
+}
+
+// DeleteAdminUser removes an admin user, or returns ErrNotFound
+func (r *Repository) DeleteAdminUser(ctx context.Context, email string) (err error) {
+	ctx, op := r.begin(ctx, "DeleteAdminUser")
+	defer op.end(&err)
+	_, err = r.adminsColl.Doc(adminID(email)).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// AuthenticateAdmin returns the admin user with email if password matches,
+// or ErrInvalidCredentials
+func (r *Repository) AuthenticateAdmin(ctx context.Context, email, password string) (_ *models.AdminUser, err error) {
+	ctx, op := r.begin(ctx, "AuthenticateAdmin")
+	defer op.end(&err)
+	id := adminID(email)
+	if id == "" || strings.Contains(id, "/") {
+		return nil, ErrInvalidCredentials
+	}
+	doc, err := r.adminsColl.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var admin models.AdminUser
+	if err := doc.DataTo(&admin); err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &admin, nil
+}
@@ -0,0 +1,435 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ArchiveFormat identifies event archives
+	ArchiveFormat = "workshop-event-archive"
+	// ArchiveVersion is the archive layout written by ExportEvent and the
+	// only one RestoreEvent accepts
+	ArchiveVersion = 1
+
+	// getAllBatch bounds the documents fetched per GetAll call
+	getAllBatch = 300
+)
+
+// ErrInvalidArchive means an archive failed validation. The restore
+// report lists the problems.
+var ErrInvalidArchive = &kindError{"invalid archive", ErrValidation}
+
+// archiveRequired lists fields that documents of the main collections must
+// have to be restored
+var archiveRequired = map[string][]string{
+	KindAttendees: {"Name", "Email"},
+	KindSpeakers:  {"Name"},
+	KindSessions:  {"Title"},
+}
+
+// Backup operations
+
+// ExportEvent reads the event document and every collection below it,
+// recursively, into an archive
+func (r *Repository) ExportEvent(ctx context.Context) (_ *models.EventArchive, err error) {
+	ctx, op := r.begin(ctx, "ExportEvent")
+	defer op.end(&err)
+	archive := &models.EventArchive{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		EventID:    r.subDocID,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	snap, err := r.eventDoc.Get(ctx)
+	switch {
+	case err == nil:
+		if archive.Settings, err = encodeFields(snap.Data()); err != nil {
+			return nil, fmt.Errorf("settings: %w", err)
+		}
+	case status.Code(err) != codes.NotFound:
+		return nil, err
+	}
+
+	if archive.Collections, err = r.exportCollections(ctx, r.eventDoc, ""); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// unarchivedCollections are the event collections left out of archives:
+// short-lived request state, and webhooks, whose live endpoints and signing
+// secrets must not be copied into a restored or cloned event. Restores
+// skip them in archives written before they were left out.
+var unarchivedCollections = map[string]bool{
+	"idempotency":       true,
+	"jobs":              true,
+	"leases":            true,
+	"outbox":            true,
+	"outboxDeadLetters": true,
+	"webhooks":          true,
+	"webhookDeliveries": true,
+}
+
+func (r *Repository) exportCollections(ctx context.Context, parent *firestore.DocumentRef, prefix string) (map[string][]models.ArchiveDocument, error) {
+	collections := make(map[string][]models.ArchiveDocument)
+	it := parent.Collections(ctx)
+	for {
+		coll, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if prefix == "" && unarchivedCollections[coll.ID] {
+			continue
+		}
+
+		// Listing references includes documents that only exist as the
+		// parent of subcollections
+		refs, err := coll.DocumentRefs(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		docs := make([]models.ArchiveDocument, 0, len(refs))
+		for start := 0; start < len(refs); start += getAllBatch {
+			snaps, err := r.client.GetAll(ctx, refs[start:min(start+getAllBatch, len(refs))])
+			if err != nil {
+				return nil, err
+			}
+			for _, snap := range snaps {
+				path := prefix + coll.ID + "/" + snap.Ref.ID
+				doc := models.ArchiveDocument{ID: snap.Ref.ID}
+				if snap.Exists() {
+					if doc.Data, err = encodeFields(snap.Data()); err != nil {
+						return nil, fmt.Errorf("%s: %w", path, err)
+					}
+				}
+				if doc.Collections, err = r.exportCollections(ctx, snap.Ref, path+"/"); err != nil {
+					return nil, err
+				}
+				if len(doc.Collections) == 0 {
+					doc.Collections = nil
+				}
+				docs = append(docs, doc)
+			}
+		}
+		collections[coll.ID] = docs
+	}
+	return collections, nil
+}
+
+// restoreWrite is a document write planned by RestoreEvent. Path is
+// relative to the event document.
+type restoreWrite struct {
+	ref  *firestore.DocumentRef
+	path string
+	data map[string]interface{}
+}
+
+// RestoreEvent writes an archive into this repository's event, which may
+// differ from the event it was exported from. Documents that already exist
+// are skipped, overwritten or make the restore fail, depending on
+// onConflict. A dry run only reports what would happen, including the
+// conflicts. It returns ErrInvalidArchive if validation fails and
+// ErrAlreadyExists if onConflict is ConflictFail and documents exist; the
+// report says why.
+func (r *Repository) RestoreEvent(ctx context.Context, archive *models.EventArchive, onConflict string, dryRun bool) (_ *models.RestoreReport, err error) {
+	ctx, op := r.begin(ctx, "RestoreEvent")
+	defer op.end(&err)
+	report := &models.RestoreReport{EventID: r.subDocID, DryRun: dryRun, OnConflict: onConflict}
+
+	writes, problems := r.planRestore(archive)
+	switch onConflict {
+	case models.ConflictSkip, models.ConflictOverwrite, models.ConflictFail:
+	default:
+		problems = append(problems, fmt.Sprintf("conflict strategy must be %s, %s or %s", models.ConflictSkip, models.ConflictOverwrite, models.ConflictFail))
+	}
+	if len(problems) > 0 {
+		report.Errors = problems
+		return report, ErrInvalidArchive
+	}
+
+	pending := make([]restoreWrite, 0, len(writes))
+	for start := 0; start < len(writes); start += getAllBatch {
+		batch := writes[start:min(start+getAllBatch, len(writes))]
+		refs := make([]*firestore.DocumentRef, len(batch))
+		for i, w := range batch {
+			refs[i] = w.ref
+		}
+		snaps, err := r.client.GetAll(ctx, refs)
+		if err != nil {
+			return report, err
+		}
+
+		for i, w := range batch {
+			if !snaps[i].Exists() {
+				report.Created++
+				pending = append(pending, w)
+				continue
+			}
+			report.Conflicts = append(report.Conflicts, w.path)
+			switch onConflict {
+			case models.ConflictSkip:
+				report.Skipped++
+			case models.ConflictOverwrite:
+				report.Overwritten++
+				pending = append(pending, w)
+			}
+		}
+	}
+	if dryRun {
+		return report, nil
+	}
+	if onConflict == models.ConflictFail && len(report.Conflicts) > 0 {
+		return report, ErrAlreadyExists
+	}
+
+	bw := r.client.BulkWriter(ctx)
+	jobs := make([]*firestore.BulkWriterJob, 0, len(pending))
+	for _, w := range pending {
+		job, err := bw.Set(w.ref, w.data)
+		if err != nil {
+			bw.End()
+			return report, fmt.Errorf("%s: %w", w.path, err)
+		}
+		jobs = append(jobs, job)
+	}
+	bw.End()
+	for i, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			return report, fmt.Errorf("%s: %w", pending[i].path, err)
+		}
+	}
+
+	// Skipped documents can leave the archived counters out of step with
+	// the attendees
+	if _, err := r.recomputeCounters(ctx); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// planRestore validates an archive and turns it into document writes,
+// returning the problems found instead if there are any
+func (r *Repository) planRestore(archive *models.EventArchive) ([]restoreWrite, []string) {
+	var problems []string
+	if archive.Format != ArchiveFormat {
+		problems = append(problems, fmt.Sprintf("format must be %q", ArchiveFormat))
+	}
+	if archive.Version != ArchiveVersion {
+		problems = append(problems, fmt.Sprintf("unsupported archive version %d, expected %d", archive.Version, ArchiveVersion))
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	var writes []restoreWrite
+	if archive.Settings != nil {
+		data, err := decodeFields(archive.Settings)
+		if err != nil {
+			problems = append(problems, "settings: "+err.Error())
+		} else {
+			writes = append(writes, restoreWrite{ref: r.eventDoc, path: "(settings)", data: data})
+		}
+	}
+	planCollections(r.eventDoc, "", archive.Collections, &writes, &problems)
+
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	return writes, nil
+}
+
+func planCollections(parent *firestore.DocumentRef, prefix string, collections map[string][]models.ArchiveDocument, writes *[]restoreWrite, problems *[]string) {
+	names := make([]string, 0, len(collections))
+	for name := range collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if prefix == "" && unarchivedCollections[name] {
+			continue
+		}
+		if !validDocumentID(name) {
+			*problems = append(*problems, fmt.Sprintf("%s%s: invalid collection ID", prefix, name))
+			continue
+		}
+
+		seen := make(map[string]bool, len(collections[name]))
+		for _, doc := range collections[name] {
+			path := prefix + name + "/" + doc.ID
+			if !validDocumentID(doc.ID) {
+				*problems = append(*problems, fmt.Sprintf("%s: invalid document ID", path))
+				continue
+			}
+			if seen[doc.ID] {
+				*problems = append(*problems, fmt.Sprintf("%s: duplicate document", path))
+				continue
+			}
+			seen[doc.ID] = true
+
+			ref := parent.Collection(name).Doc(doc.ID)
+			if doc.Data != nil {
+				data, err := decodeFields(doc.Data)
+				if err != nil {
+					*problems = append(*problems, fmt.Sprintf("%s: %v", path, err))
+					continue
+				}
+				if prefix == "" {
+					for _, field := range archiveRequired[name] {
+						if v, ok := data[field]; !ok || v == "" || v == nil {
+							*problems = append(*problems, fmt.Sprintf("%s: missing %s", path, field))
+						}
+					}
+				}
+				*writes = append(*writes, restoreWrite{ref: ref, path: path, data: data})
+			}
+			planCollections(ref, path+"/", doc.Collections, writes, problems)
+		}
+	}
+}
+
+// ValidEventID reports whether id can name an event
+func ValidEventID(id string) bool {
+	return validDocumentID(id)
+}
+
+// validDocumentID reports whether id can name a Firestore document or
+// collection
+func validDocumentID(id string) bool {
+	if id == "" || id == "." || id == ".." || len(id) > 1500 || strings.Contains(id, "/") {
+		return false
+	}
+	return !(strings.HasPrefix(id, "__") && strings.HasSuffix(id, "__"))
+}
+
+// encodeFields converts Firestore document data to archive values
+func encodeFields(fields map[string]interface{}) (map[string]interface{}, error) {
+	encoded := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		ev, err := encodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", k, err)
+		}
+		encoded[k] = ev
+	}
+	return encoded, nil
+}
+
+func encodeValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string, int64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("cannot archive %v", v)
+		}
+		return map[string]interface{}{"$double": v}, nil
+	case time.Time:
+		return map[string]interface{}{"$timestamp": v.UTC().Format(time.RFC3339Nano)}, nil
+	case []byte:
+		return map[string]interface{}{"$bytes": base64.StdEncoding.EncodeToString(v)}, nil
+	case []interface{}:
+		encoded := make([]interface{}, len(v))
+		for i, e := range v {
+			ev, err := encodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			encoded[i] = ev
+		}
+		return encoded, nil
+	case map[string]interface{}:
+		return encodeFields(v)
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// decodeFields converts archive values back to Firestore document data.
+// Numbers may be json.Number or float64; whole numbers become integers.
+func decodeFields(fields map[string]interface{}) (map[string]interface{}, error) {
+	decoded := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		dv, err := decodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", k, err)
+		}
+		decoded[k] = dv
+	}
+	return decoded, nil
+}
+
+func decodeValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, bool, string:
+		return v, nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v), nil
+		}
+		return v, nil
+	case []interface{}:
+		decoded := make([]interface{}, len(v))
+		for i, e := range v {
+			dv, err := decodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			decoded[i] = dv
+		}
+		return decoded, nil
+	case map[string]interface{}:
+		if len(v) == 1 {
+			if t, ok := v["$timestamp"]; ok {
+				s, _ := t.(string)
+				ts, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid $timestamp %v", t)
+				}
+				return ts, nil
+			}
+			if d, ok := v["$double"]; ok {
+				switch d := d.(type) {
+				case json.Number:
+					return d.Float64()
+				case float64:
+					return d, nil
+				}
+				return nil, fmt.Errorf("invalid $double %v", d)
+			}
+			if b, ok := v["$bytes"]; ok {
+				s, _ := b.(string)
+				data, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return nil, fmt.Errorf("invalid $bytes")
+				}
+				return data, nil
+			}
+		}
+		return decodeFields(v)
+	}
+	return nil, fmt.Errorf("unsupported value of type %T", v)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+)
+
+// attendeeCounterShards spreads counter writes over several documents so
+// bursts of registrations do not contend on a single one
+const attendeeCounterShards = 10
+
+// attendeeCounter is one shard of the attendee counters. Designations holds
+// the materialized per-designation totals. The totals are the sum over
+// every shard.
+type attendeeCounter struct {
+	Count        int64
+	Designations map[string]int64
+}
+
+// countAttendee adds delta to a random counter shard within tx
+func (r *Repository) countAttendee(tx *firestore.Transaction, designation string, delta int64) error {
+	update := map[string]interface{}{"Count": firestore.Increment(delta)}
+	if designation != "" {
+		update["Designations"] = map[string]interface{}{designation: firestore.Increment(delta)}
+	}
+	shard := r.counterShards.Doc(strconv.Itoa(rand.Intn(attendeeCounterShards)))
+	return tx.Set(shard, update, firestore.MergeAll)
+}
+
+// attendeeTotals sums the counter shards. Counters that have never been
+// computed, e.g. for an event that predates them, are rebuilt first.
+func (r *Repository) attendeeTotals(ctx context.Context) (*attendeeCounter, error) {
+	refs := make([]*firestore.DocumentRef, 0, attendeeCounterShards+1)
+	refs = append(refs, r.countersDoc)
+	for i := 0; i < attendeeCounterShards; i++ {
+		refs = append(refs, r.counterShards.Doc(strconv.Itoa(i)))
+	}
+	docs, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	if !docs[0].Exists() {
+		return r.recomputeCounters(ctx)
+	}
+
+	totals := &attendeeCounter{Designations: make(map[string]int64)}
+	for _, doc := range docs[1:] {
+		if !doc.Exists() {
+			continue
+		}
+		var shard attendeeCounter
+		if err := doc.DataTo(&shard); err != nil {
+			return nil, err
+		}
+		totals.Count += shard.Count
+		for designation, n := range shard.Designations {
+			totals.Designations[designation] += n
+		}
+	}
+	return totals, nil
+}
+
+// RecomputeCounters rebuilds the attendee counters from the attendee
+// documents in one transaction and returns the attendee count
+func (r *Repository) RecomputeCounters(ctx context.Context) (_ int, err error) {
+	ctx, op := r.begin(ctx, "RecomputeCounters")
+	defer op.end(&err)
+	totals, err := r.recomputeCounters(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int(totals.Count), nil
+}
+
+func (r *Repository) recomputeCounters(ctx context.Context) (*attendeeCounter, error) {
+	var totals *attendeeCounter
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(r.attendeesColl).GetAll()
+		if err != nil {
+			return err
+		}
+
+		totals = &attendeeCounter{Designations: make(map[string]int64)}
+		for _, doc := range docs {
+			var attendee models.Attendee
+			if err := doc.DataTo(&attendee); err != nil {
+				continue
+			}
+			totals.Count++
+			if attendee.Designation != "" {
+				totals.Designations[attendee.Designation]++
+			}
+		}
+
+		// The totals go into the first shard and the others are reset
+		for i := 0; i < attendeeCounterShards; i++ {
+			shard := attendeeCounter{Designations: map[string]int64{}}
+			if i == 0 {
+				shard = *totals
+			}
+			if err := tx.Set(r.counterShards.Doc(strconv.Itoa(i)), shard); err != nil {
+				return err
+			}
+		}
+		return tx.Set(r.countersDoc, map[string]interface{}{"RecomputedAt": time.Now()})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kinds of domain errors. Every expected failure returned by the
+// repository matches one of them with errors.Is, while the original
+// Firestore error stays wrapped for logging. Errors matching none of them
+// are unexpected.
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the write conflicts with the stored state
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the input was rejected
+	ErrValidation = errors.New("validation failed")
+	// ErrUnavailable means storage could not be reached in time; retrying
+	// later may succeed
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// Specific domain errors, each of one kind
+var (
+	ErrAlreadyExists = &kindError{"already exists", ErrConflict}
+	// ErrVersionConflict means the document changed since the caller read it
+	ErrVersionConflict = &kindError{"version conflict", ErrConflict}
+	// ErrClaimLost means another request took over an idempotency key
+	// whose lease had lapsed
+	ErrClaimLost = &kindError{"idempotency claim lost", ErrConflict}
+	// ErrInvalidCredentials means the admin email or password is wrong
+	ErrInvalidCredentials = &kindError{"invalid credentials", ErrValidation}
+)
+
+// kindError is a specific error that also matches its kind
+type kindError struct {
+	msg  string
+	kind error
+}
+
+func (e *kindError) Error() string        { return e.msg }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+
+// isDomain reports whether err already matches a kind of domain error
+func isDomain(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) ||
+		errors.Is(err, ErrValidation) || errors.Is(err, ErrUnavailable)
+}
+
+// classify turns the Firestore, gRPC or context error in *errp into a
+// domain error wrapping it. Exported methods defer it on their named error
+// result.
+func classify(errp *error) {
+	err := *errp
+	if err == nil || isDomain(err) {
+		return
+	}
+
+	var kind error
+	switch status.Code(err) {
+	case codes.NotFound:
+		kind = ErrNotFound
+	case codes.AlreadyExists:
+		kind = ErrAlreadyExists
+	case codes.Aborted:
+		kind = ErrConflict
+	case codes.InvalidArgument, codes.OutOfRange:
+		kind = ErrValidation
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Canceled:
+		kind = ErrUnavailable
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		kind = ErrUnavailable
+	}
+	if kind != nil {
+		*errp = fmt.Errorf("%w: %w", kind, err)
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"sort"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Feedback operations
+
+// CreateFeedback stores feedback keyed by session and attendee, returning
+// ErrAlreadyExists if the attendee has already rated the session
+func (r *Repository) CreateFeedback(ctx context.Context, feedback *models.Feedback) (err error) {
+	feedback.ID = feedback.SessionID + "_" + feedback.AttendeeID
+	ctx, op := r.begin(ctx, "CreateFeedback", attribute.String("feedback.id", feedback.ID), attribute.String("session.id", feedback.SessionID))
+	defer op.end(&err)
+	_, err = r.feedbackColl.Doc(feedback.ID).Create(ctx, feedback)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *Repository) GetAllFeedback(ctx context.Context) (_ []models.Feedback, err error) {
+	ctx, op := r.begin(ctx, "GetAllFeedback")
+	defer op.end(&err)
+	docs, err := r.feedbackColl.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	feedback := make([]models.Feedback, 0, len(docs))
+	for _, doc := range docs {
+		var f models.Feedback
+		if err := doc.DataTo(&f); err != nil {
+			continue
+		}
+		f.ID = doc.Ref.ID
+		feedback = append(feedback, f)
+	}
+
+	sort.Slice(feedback, func(i, j int) bool {
+		return feedback[i].SubmittedAt.Before(feedback[j].SubmittedAt)
+	})
+	return feedback, nil
+}
+
+// GetSessionFeedbackSummaries aggregates feedback per session. When
+// sessionID is set only that session is returned, including comments.
+func (r *Repository) GetSessionFeedbackSummaries(ctx context.Context, sessionID string) (_ []models.FeedbackSummary, err error) {
+	ctx, op := r.begin(ctx, "GetSessionFeedbackSummaries", attribute.String("session.id", sessionID))
+	defer op.end(&err)
+	sessions, err := r.GetAllSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feedback, err := r.GetAllFeedback(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bySession := make(map[string][]models.Feedback)
+	for _, f := range feedback {
+		bySession[f.SessionID] = append(bySession[f.SessionID], f)
+	}
+
+	summaries := make([]models.FeedbackSummary, 0, len(sessions))
+	for _, session := range sessions {
+		if sessionID != "" && session.ID != sessionID {
+			continue
+		}
+		summaries = append(summaries, summarizeFeedback(session.ID, session.Title, bySession[session.ID], sessionID != ""))
+	}
+	return summaries, nil
+}
+
+// GetSpeakerFeedbackSummaries aggregates feedback across every session of
+// each speaker. When speakerID is set only that speaker is returned,
+// including comments.
+func (r *Repository) GetSpeakerFeedbackSummaries(ctx context.Context, speakerID string) (_ []models.FeedbackSummary, err error) {
+	ctx, op := r.begin(ctx, "GetSpeakerFeedbackSummaries", attribute.String("speaker.id", speakerID))
+	defer op.end(&err)
+	speakers, err := r.GetAllSpeakers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := r.GetAllSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	feedback, err := r.GetAllFeedback(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	speakersBySession := make(map[string][]string)
+	for _, session := range sessions {
+		speakersBySession[session.ID] = session.SpeakerIDs
+	}
+	bySpeaker := make(map[string][]models.Feedback)
+	for _, f := range feedback {
+		for _, id := range speakersBySession[f.SessionID] {
+			bySpeaker[id] = append(bySpeaker[id], f)
+		}
+	}
+
+	summaries := make([]models.FeedbackSummary, 0, len(speakers))
+	for _, speaker := range speakers {
+		if speakerID != "" && speaker.ID != speakerID {
+			continue
+		}
+		summaries = append(summaries, summarizeFeedback(speaker.ID, speaker.Name, bySpeaker[speaker.ID], speakerID != ""))
+	}
+	return summaries, nil
+}
+
+func summarizeFeedback(id, name string, feedback []models.Feedback, withComments bool) models.FeedbackSummary {
+	summary := models.FeedbackSummary{ID: id, Name: name, Responses: len(feedback)}
+
+	total := 0
+	for _, f := range feedback {
+		if f.Rating >= 1 && f.Rating <= 5 {
+			summary.Distribution[f.Rating-1]++
+		}
+		total += f.Rating
+		if withComments && f.Comment != "" {
+			summary.Comments = append(summary.Comments, f)
+		}
+	}
+	if len(feedback) > 0 {
+		summary.AverageRating = float64(total) / float64(len(feedback))
+	}
+	return summary
+}
@@ -3,19 +3,43 @@ package repository
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"appdirect-workshop-backend/internal/models"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Repository struct {
-	client         *firestore.Client
-	subDocID       string
-	attendeesColl  *firestore.CollectionRef
-	speakersColl   *firestore.CollectionRef
-	sessionsColl   *firestore.CollectionRef
+	client          *firestore.Client
+	subDocID        string
+	eventDoc        *firestore.DocumentRef
+	attendeesColl   *firestore.CollectionRef
+	speakersColl    *firestore.CollectionRef
+	sessionsColl    *firestore.CollectionRef
+	feedbackColl    *firestore.CollectionRef
+	questionsColl   *firestore.CollectionRef
+	pollsColl       *firestore.CollectionRef
+	countersDoc     *firestore.DocumentRef
+	counterShards   *firestore.CollectionRef
+	trashColl       *firestore.CollectionRef
+	migrationsColl  *firestore.CollectionRef
+	adminsColl      *firestore.CollectionRef
+	idempotencyColl *firestore.CollectionRef
+	webhooksColl    *firestore.CollectionRef
+	deliveriesColl  *firestore.CollectionRef
+	outboxColl      *firestore.CollectionRef
+	deadLettersColl *firestore.CollectionRef
+	jobsColl        *firestore.CollectionRef
+	leasesColl      *firestore.CollectionRef
+	snapshotsColl   *firestore.CollectionRef
 }
 
 func NewRepository(ctx context.Context, projectID, subDocID, serviceAccountPath string) (*Repository, error) {
@@ -35,31 +59,97 @@ func NewRepository(ctx context.Context, projectID, subDocID, serviceAccountPath
 		return nil, fmt.Errorf("failed to create firestore client: %w", err)
 	}
 
+	return newEventRepository(client, subDocID), nil
+}
+
+// ForEvent returns a repository for another event that shares this
+// repository's client. Close only the original repository.
+func (r *Repository) ForEvent(subDocID string) *Repository {
+	return newEventRepository(r.client, subDocID)
+}
+
+// EventID returns the ID of the event this repository reads and writes
+func (r *Repository) EventID() string {
+	return r.subDocID
+}
+
+func newEventRepository(client *firestore.Client, subDocID string) *Repository {
 	// Create subcollection reference
 	docRef := client.Collection("workshop").Doc(subDocID)
-	
-	repo := &Repository{
-		client:        client,
-		subDocID:      subDocID,
-		attendeesColl: docRef.Collection("attendees"),
-		speakersColl:  docRef.Collection("speakers"),
-		sessionsColl:  docRef.Collection("sessions"),
+	countersDoc := docRef.Collection("counters").Doc("attendees")
+
+	return &Repository{
+		client:          client,
+		subDocID:        subDocID,
+		eventDoc:        docRef,
+		attendeesColl:   docRef.Collection("attendees"),
+		speakersColl:    docRef.Collection("speakers"),
+		sessionsColl:    docRef.Collection("sessions"),
+		feedbackColl:    docRef.Collection("feedback"),
+		questionsColl:   docRef.Collection("questions"),
+		pollsColl:       docRef.Collection("polls"),
+		countersDoc:     countersDoc,
+		counterShards:   countersDoc.Collection("shards"),
+		trashColl:       docRef.Collection("trash"),
+		migrationsColl:  docRef.Collection("migrations"),
+		adminsColl:      client.Collection("admins"),
+		idempotencyColl: docRef.Collection("idempotency"),
+		webhooksColl:    docRef.Collection("webhooks"),
+		deliveriesColl:  docRef.Collection("webhookDeliveries"),
+		outboxColl:      docRef.Collection("outbox"),
+		deadLettersColl: docRef.Collection("outboxDeadLetters"),
+		jobsColl:        docRef.Collection("jobs"),
+		leasesColl:      docRef.Collection("leases"),
+		snapshotsColl:   docRef.Collection("analyticsSnapshots"),
 	}
-
-	return repo, nil
 }
 
 func (r *Repository) Close() error {
 	return r.client.Close()
 }
 
-// Attendee operations
-func (r *Repository) CreateAttendee(ctx context.Context, attendee *models.Attendee) error {
-	_, err := r.attendeesColl.Doc(attendee.ID).Set(ctx, attendee)
+// Ping checks that Firestore can be reached with the repository's
+// credentials by reading the event document, which need not exist
+func (r *Repository) Ping(ctx context.Context) (err error) {
+	ctx, op := r.begin(ctx, "Ping")
+	defer op.end(&err)
+	_, err = r.eventDoc.Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil
+	}
 	return err
 }
 
-func (r *Repository) GetAttendee(ctx context.Context, id string) (*models.Attendee, error) {
+// Attendee operations
+
+// normalizeEmail returns an email as attendees and admins are stored and
+// looked up: trimmed and lower-cased
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CreateAttendee stores an attendee with a normalized email, updates the
+// attendee counters and writes an attendee.registered event in one
+// transaction. It returns ErrAlreadyExists, counting nothing, if an
+// attendee with the same ID exists.
+func (r *Repository) CreateAttendee(ctx context.Context, attendee *models.Attendee) (err error) {
+	ctx, op := r.begin(ctx, "CreateAttendee", attribute.String("attendee.id", attendee.ID))
+	defer op.end(&err)
+	attendee.Email = normalizeEmail(attendee.Email)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(r.attendeesColl.Doc(attendee.ID), attendee); err != nil {
+			return err
+		}
+		if err := r.countAttendee(tx, attendee.Designation, 1); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventAttendeeRegistered, attendee)
+	})
+}
+
+func (r *Repository) GetAttendee(ctx context.Context, id string) (_ *models.Attendee, err error) {
+	ctx, op := r.begin(ctx, "GetAttendee", attribute.String("attendee.id", id))
+	defer op.end(&err)
 	doc, err := r.attendeesColl.Doc(id).Get(ctx)
 	if err != nil {
 		return nil, err
@@ -73,7 +163,30 @@ func (r *Repository) GetAttendee(ctx context.Context, id string) (*models.Attend
 	return &attendee, nil
 }
 
-func (r *Repository) GetAllAttendees(ctx context.Context) ([]models.Attendee, error) {
+// GetAttendeeByEmail returns the attendee registered with email, compared
+// case-insensitively, or ErrNotFound
+func (r *Repository) GetAttendeeByEmail(ctx context.Context, email string) (_ *models.Attendee, err error) {
+	ctx, op := r.begin(ctx, "GetAttendeeByEmail")
+	defer op.end(&err)
+	doc, err := r.attendeesColl.Where("Email", "==", normalizeEmail(email)).Limit(1).Documents(ctx).Next()
+	if err == iterator.Done {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attendee models.Attendee
+	if err := doc.DataTo(&attendee); err != nil {
+		return nil, err
+	}
+	attendee.ID = doc.Ref.ID
+	return &attendee, nil
+}
+
+func (r *Repository) GetAllAttendees(ctx context.Context) (_ []models.Attendee, err error) {
+	ctx, op := r.begin(ctx, "GetAllAttendees")
+	defer op.end(&err)
 	docs, err := r.attendeesColl.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
@@ -92,26 +205,67 @@ func (r *Repository) GetAllAttendees(ctx context.Context) ([]models.Attendee, er
 	return attendees, nil
 }
 
-func (r *Repository) GetAttendeeCount(ctx context.Context) (int, error) {
-	docs, err := r.attendeesColl.Documents(ctx).GetAll()
+// GetAttendeeCount counts attendees with an aggregation query, falling back
+// to the attendee counters where aggregation queries are unavailable (e.g.
+// older emulators)
+func (r *Repository) GetAttendeeCount(ctx context.Context) (_ int, err error) {
+	ctx, op := r.begin(ctx, "GetAttendeeCount")
+	defer op.end(&err)
+	result, err := r.attendeesColl.NewAggregationQuery().WithCount("count").Get(ctx)
+	if status.Code(err) == codes.Unimplemented {
+		totals, err := r.attendeeTotals(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return int(totals.Count), nil
+	}
 	if err != nil {
 		return 0, err
 	}
-	return len(docs), nil
+
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count aggregation result %T", result["count"])
+	}
+	return int(count.GetIntegerValue()), nil
 }
 
-func (r *Repository) DeleteAttendee(ctx context.Context, id string) error {
-	_, err := r.attendeesColl.Doc(id).Delete(ctx)
-	return err
+// DeleteAttendee moves an attendee to the trash, updates the attendee
+// counters and writes an attendee.deleted event in one transaction. It
+// returns ErrNotFound if there is no such attendee.
+func (r *Repository) DeleteAttendee(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "DeleteAttendee", attribute.String("attendee.id", id))
+	defer op.end(&err)
+	return r.moveToTrash(ctx, KindAttendees, id, func(tx *firestore.Transaction, doc *firestore.DocumentSnapshot) error {
+		var attendee models.Attendee
+		if err := doc.DataTo(&attendee); err != nil {
+			return err
+		}
+		if err := r.countAttendee(tx, attendee.Designation, -1); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventAttendeeDeleted, deletedEvent(id))
+	})
 }
 
-// Speaker operations
-func (r *Repository) CreateSpeaker(ctx context.Context, speaker *models.Speaker) error {
-	_, err := r.speakersColl.Doc(speaker.ID).Set(ctx, speaker)
-	return err
+// Speaker operations. Every change also writes a speaker event to the
+// outbox in the same transaction. CreateSpeaker returns ErrAlreadyExists
+// if a speaker with the same ID exists.
+func (r *Repository) CreateSpeaker(ctx context.Context, speaker *models.Speaker) (err error) {
+	ctx, op := r.begin(ctx, "CreateSpeaker", attribute.String("speaker.id", speaker.ID))
+	defer op.end(&err)
+	speaker.Version = 1
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(r.speakersColl.Doc(speaker.ID), speaker); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventSpeakerCreated, speaker)
+	})
 }
 
-func (r *Repository) GetSpeaker(ctx context.Context, id string) (*models.Speaker, error) {
+func (r *Repository) GetSpeaker(ctx context.Context, id string) (_ *models.Speaker, err error) {
+	ctx, op := r.begin(ctx, "GetSpeaker", attribute.String("speaker.id", id))
+	defer op.end(&err)
 	doc, err := r.speakersColl.Doc(id).Get(ctx)
 	if err != nil {
 		return nil, err
@@ -125,7 +279,9 @@ func (r *Repository) GetSpeaker(ctx context.Context, id string) (*models.Speaker
 	return &speaker, nil
 }
 
-func (r *Repository) GetAllSpeakers(ctx context.Context) ([]models.Speaker, error) {
+func (r *Repository) GetAllSpeakers(ctx context.Context) (_ []models.Speaker, err error) {
+	ctx, op := r.begin(ctx, "GetAllSpeakers")
+	defer op.end(&err)
 	docs, err := r.speakersColl.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
@@ -144,24 +300,91 @@ func (r *Repository) GetAllSpeakers(ctx context.Context) ([]models.Speaker, erro
 	return speakers, nil
 }
 
-func (r *Repository) UpdateSpeaker(ctx context.Context, id string, speaker *models.Speaker) error {
-	speaker.ID = id
-	_, err := r.speakersColl.Doc(id).Set(ctx, speaker)
-	return err
+// UpdateSpeaker replaces a speaker if it is still at version and bumps the
+// version. It returns ErrNotFound or ErrVersionConflict.
+func (r *Repository) UpdateSpeaker(ctx context.Context, id string, speaker *models.Speaker, version int64) (err error) {
+	ctx, op := r.begin(ctx, "UpdateSpeaker", attribute.String("speaker.id", id))
+	defer op.end(&err)
+	ref := r.speakersColl.Doc(id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := checkVersion(tx, ref, version); err != nil {
+			return err
+		}
+		speaker.ID = id
+		speaker.Version = version + 1
+		if err := tx.Set(ref, speaker); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventSpeakerUpdated, speaker)
+	})
 }
 
-func (r *Repository) DeleteSpeaker(ctx context.Context, id string) error {
-	_, err := r.speakersColl.Doc(id).Delete(ctx)
-	return err
+// SetSpeakerPhoto replaces the uploaded photo of a speaker, or clears it
+// when photo is nil, without touching the other fields. photoURL picks the
+// new PhotoURL from the stored speaker. It returns the updated speaker and
+// the photo it replaced, if any.
+func (r *Repository) SetSpeakerPhoto(ctx context.Context, id string, photo *models.SpeakerPhoto, photoURL func(current *models.Speaker) string) (_ *models.Speaker, previous *models.SpeakerPhoto, err error) {
+	ctx, op := r.begin(ctx, "SetSpeakerPhoto", attribute.String("speaker.id", id))
+	defer op.end(&err)
+	ref := r.speakersColl.Doc(id)
+	var speaker models.Speaker
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+		speaker = models.Speaker{}
+		if err := doc.DataTo(&speaker); err != nil {
+			return err
+		}
+		previous = speaker.Photo
+		speaker.ID = id
+		speaker.PhotoURL = photoURL(&speaker)
+		speaker.Photo = photo
+		speaker.Version++
+
+		if err := tx.Update(ref, []firestore.Update{
+			{Path: "Photo", Value: photo},
+			{Path: "PhotoURL", Value: speaker.PhotoURL},
+			{Path: "Version", Value: speaker.Version},
+		}); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventSpeakerUpdated, &speaker)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &speaker, previous, nil
 }
 
-// Session operations
-func (r *Repository) CreateSession(ctx context.Context, session *models.Session) error {
-	_, err := r.sessionsColl.Doc(session.ID).Set(ctx, session)
-	return err
+// DeleteSpeaker moves a speaker to the trash, or returns ErrNotFound
+func (r *Repository) DeleteSpeaker(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "DeleteSpeaker", attribute.String("speaker.id", id))
+	defer op.end(&err)
+	return r.moveToTrash(ctx, KindSpeakers, id, func(tx *firestore.Transaction, _ *firestore.DocumentSnapshot) error {
+		return r.addOutboxEvent(tx, models.EventSpeakerDeleted, deletedEvent(id))
+	})
 }
 
-func (r *Repository) GetSession(ctx context.Context, id string) (*models.Session, error) {
+// Session operations. Every change also writes a session event to the
+// outbox in the same transaction. CreateSession returns ErrAlreadyExists
+// if a session with the same ID exists.
+func (r *Repository) CreateSession(ctx context.Context, session *models.Session) (err error) {
+	ctx, op := r.begin(ctx, "CreateSession", attribute.String("session.id", session.ID))
+	defer op.end(&err)
+	session.Version = 1
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Create(r.sessionsColl.Doc(session.ID), session); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventSessionCreated, session)
+	})
+}
+
+func (r *Repository) GetSession(ctx context.Context, id string) (_ *models.Session, err error) {
+	ctx, op := r.begin(ctx, "GetSession", attribute.String("session.id", id))
+	defer op.end(&err)
 	doc, err := r.sessionsColl.Doc(id).Get(ctx)
 	if err != nil {
 		return nil, err
@@ -175,7 +398,9 @@ func (r *Repository) GetSession(ctx context.Context, id string) (*models.Session
 	return &session, nil
 }
 
-func (r *Repository) GetAllSessions(ctx context.Context) ([]models.Session, error) {
+func (r *Repository) GetAllSessions(ctx context.Context) (_ []models.Session, err error) {
+	ctx, op := r.begin(ctx, "GetAllSessions")
+	defer op.end(&err)
 	docs, err := r.sessionsColl.Documents(ctx).GetAll()
 	if err != nil {
 		return nil, err
@@ -194,19 +419,59 @@ func (r *Repository) GetAllSessions(ctx context.Context) ([]models.Session, erro
 	return sessions, nil
 }
 
-func (r *Repository) UpdateSession(ctx context.Context, id string, session *models.Session) error {
-	session.ID = id
-	_, err := r.sessionsColl.Doc(id).Set(ctx, session)
-	return err
+// UpdateSession replaces a session if it is still at version and bumps the
+// version. It returns ErrNotFound or ErrVersionConflict.
+func (r *Repository) UpdateSession(ctx context.Context, id string, session *models.Session, version int64) (err error) {
+	ctx, op := r.begin(ctx, "UpdateSession", attribute.String("session.id", id))
+	defer op.end(&err)
+	ref := r.sessionsColl.Doc(id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := checkVersion(tx, ref, version); err != nil {
+			return err
+		}
+		session.ID = id
+		session.Version = version + 1
+		if err := tx.Set(ref, session); err != nil {
+			return err
+		}
+		return r.addOutboxEvent(tx, models.EventSessionUpdated, session)
+	})
 }
 
-func (r *Repository) DeleteSession(ctx context.Context, id string) error {
-	_, err := r.sessionsColl.Doc(id).Delete(ctx)
-	return err
+// checkVersion reads the document at ref within tx and checks its version.
+// Documents written before versioning count as version 0.
+func checkVersion(tx *firestore.Transaction, ref *firestore.DocumentRef, version int64) error {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var current int64
+	if v, err := doc.DataAt("Version"); err == nil {
+		current, _ = v.(int64)
+	}
+	if current != version {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteSession moves a session to the trash, or returns ErrNotFound
+func (r *Repository) DeleteSession(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "DeleteSession", attribute.String("session.id", id))
+	defer op.end(&err)
+	return r.moveToTrash(ctx, KindSessions, id, func(tx *firestore.Transaction, _ *firestore.DocumentSnapshot) error {
+		return r.addOutboxEvent(tx, models.EventSessionDeleted, deletedEvent(id))
+	})
 }
 
 // Get sessions with speaker details
-func (r *Repository) GetSessionsWithSpeakers(ctx context.Context) ([]models.SessionWithSpeakers, error) {
+func (r *Repository) GetSessionsWithSpeakers(ctx context.Context) (_ []models.SessionWithSpeakers, err error) {
+	ctx, op := r.begin(ctx, "GetSessionsWithSpeakers")
+	defer op.end(&err)
 	sessions, err := r.GetAllSessions(ctx)
 	if err != nil {
 		return nil, err
@@ -227,7 +492,7 @@ func (r *Repository) GetSessionsWithSpeakers(ctx context.Context) ([]models.Sess
 	sessionsWithSpeakers := make([]models.SessionWithSpeakers, 0, len(sessions))
 	for _, session := range sessions {
 		sessionWithSpeakers := models.SessionWithSpeakers{
-			Session: session,
+			Session:  session,
 			Speakers: make([]models.Speaker, 0),
 		}
 
@@ -244,27 +509,29 @@ func (r *Repository) GetSessionsWithSpeakers(ctx context.Context) ([]models.Sess
 }
 
 // Analytics operations
-func (r *Repository) GetDesignationBreakdown(ctx context.Context) ([]models.DesignationBreakdown, error) {
-	attendees, err := r.GetAllAttendees(ctx)
+// GetDesignationBreakdown returns the number of attendees per designation
+// from the materialized totals in the attendee counters
+func (r *Repository) GetDesignationBreakdown(ctx context.Context) (_ []models.DesignationBreakdown, err error) {
+	ctx, op := r.begin(ctx, "GetDesignationBreakdown")
+	defer op.end(&err)
+	totals, err := r.attendeeTotals(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Count by designation
-	designationMap := make(map[string]int)
-	for _, attendee := range attendees {
-		designationMap[attendee.Designation]++
-	}
-
-	// Convert to slice
-	breakdown := make([]models.DesignationBreakdown, 0, len(designationMap))
-	for designation, count := range designationMap {
+	breakdown := make([]models.DesignationBreakdown, 0, len(totals.Designations))
+	for designation, count := range totals.Designations {
+		if count <= 0 {
+			continue
+		}
 		breakdown = append(breakdown, models.DesignationBreakdown{
 			Designation: designation,
-			Count:       count,
+			Count:       int(count),
 		})
 	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		return breakdown[i].Designation < breakdown[j].Designation
+	})
 
 	return breakdown, nil
 }
-
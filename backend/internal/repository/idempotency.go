@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Idempotency records live in workshop/{id}/idempotency, keyed by a hash
+// of the endpoint and the client's key. They are not exported in event
+// archives. Expired records are ignored and may be removed with a
+// Firestore TTL policy on ExpiresAt.
+
+// ClaimIdempotencyKey returns the record stored for key. If there is none,
+// it has expired, or it is pending and its lock has lapsed because the
+// first request never finished, a pending record for fingerprint is stored
+// in its place, locked for lease and kept for ttl, and claimed is true.
+// The returned record's Claim identifies the new claim.
+func (r *Repository) ClaimIdempotencyKey(ctx context.Context, key, fingerprint string, lease, ttl time.Duration) (_ *models.IdempotencyRecord, claimed bool, err error) {
+	ctx, op := r.begin(ctx, "ClaimIdempotencyKey")
+	defer op.end(&err)
+	ref := r.idempotencyColl.Doc(key)
+	var record *models.IdempotencyRecord
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		record, claimed = nil, false
+		now := time.Now()
+
+		doc, err := tx.Get(ref)
+		switch {
+		case err == nil:
+			var existing models.IdempotencyRecord
+			if err := doc.DataTo(&existing); err != nil {
+				return err
+			}
+			live := now.Before(existing.ExpiresAt)
+			locked := existing.Completed || now.Before(existing.LockedUntil)
+			if live && locked {
+				record = &existing
+				return nil
+			}
+		case status.Code(err) != codes.NotFound:
+			return err
+		}
+
+		record = &models.IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Claim:       uuid.New().String(),
+			LockedUntil: now.Add(lease),
+			ExpiresAt:   now.Add(ttl),
+		}
+		claimed = true
+		return tx.Set(ref, record)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return record, claimed, nil
+}
+
+// CompleteIdempotencyKey stores the response of the request that claimed
+// key with record.Claim, to be replayed until the record expires. It
+// returns ErrClaimLost, storing nothing, if the claim's lease lapsed and
+// another request has taken over the key since.
+func (r *Repository) CompleteIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) (err error) {
+	ctx, op := r.begin(ctx, "CompleteIdempotencyKey")
+	defer op.end(&err)
+	ref := r.idempotencyColl.Doc(key)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		held, err := holdsIdempotencyClaim(tx, ref, record.Claim)
+		if err != nil {
+			return err
+		}
+		if !held {
+			return ErrClaimLost
+		}
+		completed := *record
+		completed.Completed = true
+		return tx.Set(ref, &completed)
+	})
+}
+
+// ReleaseIdempotencyKey removes the record of key so the request can be
+// retried, e.g. after it failed with a server error. A record claimed by
+// another request since is left alone.
+func (r *Repository) ReleaseIdempotencyKey(ctx context.Context, key string, record *models.IdempotencyRecord) (err error) {
+	ctx, op := r.begin(ctx, "ReleaseIdempotencyKey")
+	defer op.end(&err)
+	ref := r.idempotencyColl.Doc(key)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		held, err := holdsIdempotencyClaim(tx, ref, record.Claim)
+		if err != nil || !held {
+			return err
+		}
+		return tx.Delete(ref)
+	})
+}
+
+// holdsIdempotencyClaim reports whether the record at ref is still pending
+// under claim
+func holdsIdempotencyClaim(tx *firestore.Transaction, ref *firestore.DocumentRef, claim string) (bool, error) {
+	doc, err := tx.Get(ref)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var stored models.IdempotencyRecord
+	if err := doc.DataTo(&stored); err != nil {
+		return false, err
+	}
+	return !stored.Completed && stored.Claim == claim, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/metrics"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("appdirect-workshop-backend/internal/repository")
+
+// operation is a call to an exported repository method, traced as a span,
+// counted in the repository metrics and logged with the caller's logger
+type operation struct {
+	method string
+	start  time.Time
+	span   trace.Span
+	logger *slog.Logger
+}
+
+// begin starts the span of a call to method, with attrs such as the IDs
+// of the entities it reads or writes. Exported methods start with
+//
+//	ctx, op := r.begin(ctx, "Method", attrs...)
+//	defer op.end(&err)
+func (r *Repository) begin(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, *operation) {
+	attrs = append(attrs,
+		semconv.DBSystemKey.String("firestore"),
+		semconv.DBOperation(method),
+		attribute.String("workshop.event_id", r.subDocID),
+	)
+	ctx, span := tracer.Start(ctx, "repository."+method,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	logger := logging.FromContext(ctx)
+	return ctx, &operation{method: method, start: time.Now(), span: span, logger: logger}
+}
+
+// end classifies *errp like classify, records the latency and outcome of
+// the call, logs it and ends its span. Expected outcomes such as
+// ErrNotFound are noted without marking the call as failed.
+func (op *operation) end(errp *error) {
+	classify(errp)
+	kind := errorKind(*errp)
+	elapsed := time.Since(op.start)
+	metrics.ObserveRepository(op.method, elapsed, kind)
+
+	level := slog.LevelDebug
+	switch kind {
+	case "unavailable":
+		level = slog.LevelWarn
+	case "internal":
+		level = slog.LevelError
+	}
+	if op.logger.Enabled(context.Background(), level) {
+		attrs := []slog.Attr{slog.String("method", op.method), slog.Duration("latency", elapsed)}
+		if kind != "" {
+			attrs = append(attrs, slog.String("errorKind", kind), slog.Any("error", *errp))
+		}
+		op.logger.LogAttrs(context.Background(), level, "Repository call", attrs...)
+	}
+
+	if kind != "" {
+		op.span.SetAttributes(attribute.String("error.kind", kind))
+		if kind == "unavailable" || kind == "internal" {
+			op.span.RecordError(*errp)
+			op.span.SetStatus(otelcodes.Error, (*errp).Error())
+		}
+	}
+	op.span.End()
+}
+
+// errorKind names the kind of a domain error for metrics and traces, or
+// returns "" for nil
+func errorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	case errors.Is(err, ErrValidation):
+		return "validation"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	}
+	return "internal"
+}
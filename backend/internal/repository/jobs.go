@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Scheduled job state lives in jobs, keyed by job name, and leases in
+// leases. Neither is exported in event archives.
+
+// AcquireLease takes or renews the lease name for holder until ttl from
+// now, and reports whether holder has it. A lease held by someone else is
+// only taken over once it has expired.
+func (r *Repository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (acquired bool, err error) {
+	ctx, op := r.begin(ctx, "AcquireLease", attribute.String("lease.name", name))
+	defer op.end(&err)
+	ref := r.leasesColl.Doc(name)
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		acquired = false
+		now := time.Now()
+
+		doc, err := tx.Get(ref)
+		switch {
+		case err == nil:
+			var lease models.Lease
+			if err := doc.DataTo(&lease); err != nil {
+				return err
+			}
+			if lease.Holder != holder && now.Before(lease.ExpiresAt) {
+				return nil
+			}
+		case status.Code(err) != codes.NotFound:
+			return err
+		}
+
+		acquired = true
+		return tx.Set(ref, models.Lease{Holder: holder, ExpiresAt: now.Add(ttl)})
+	})
+	return acquired, err
+}
+
+// ReleaseLease gives up the lease name if holder has it, so another
+// instance can take it over without waiting for it to expire
+func (r *Repository) ReleaseLease(ctx context.Context, name, holder string) (err error) {
+	ctx, op := r.begin(ctx, "ReleaseLease", attribute.String("lease.name", name))
+	defer op.end(&err)
+	ref := r.leasesColl.Doc(name)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var lease models.Lease
+		if err := doc.DataTo(&lease); err != nil {
+			return err
+		}
+		if lease.Holder != holder {
+			return nil
+		}
+		return tx.Delete(ref)
+	})
+}
+
+// GetJobs returns every scheduled job, by name
+func (r *Repository) GetJobs(ctx context.Context) (_ []models.Job, err error) {
+	ctx, op := r.begin(ctx, "GetJobs")
+	defer op.end(&err)
+	docs, err := r.jobsColl.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]models.Job, 0, len(docs))
+	for _, doc := range docs {
+		job, err := jobFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs, nil
+}
+
+// GetJob returns a scheduled job or ErrNotFound
+func (r *Repository) GetJob(ctx context.Context, name string) (_ *models.Job, err error) {
+	ctx, op := r.begin(ctx, "GetJob", attribute.String("job.name", name))
+	defer op.end(&err)
+	doc, err := r.jobsColl.Doc(name).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jobFromDoc(doc)
+}
+
+// EnsureRecurringJob stores a recurring job that is not stored yet, or
+// whose schedule has changed, to next run at next. The run history of a
+// stored job is kept.
+func (r *Repository) EnsureRecurringJob(ctx context.Context, name, schedule string, next time.Time) (err error) {
+	ctx, op := r.begin(ctx, "EnsureRecurringJob", attribute.String("job.name", name))
+	defer op.end(&err)
+	ref := r.jobsColl.Doc(name)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return tx.Create(ref, models.Job{
+				Name:      name,
+				Kind:      name,
+				Schedule:  schedule,
+				NextRunAt: &next,
+				CreatedAt: time.Now(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+		job, err := jobFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		if job.Schedule == schedule && job.NextRunAt != nil {
+			return nil
+		}
+		return tx.Update(ref, []firestore.Update{
+			{Path: "Kind", Value: name},
+			{Path: "Schedule", Value: schedule},
+			{Path: "NextRunAt", Value: next},
+		})
+	})
+}
+
+// ScheduleJob stores a one-off job to run at job.NextRunAt, replacing any
+// job with the same name
+func (r *Repository) ScheduleJob(ctx context.Context, job *models.Job) (err error) {
+	ctx, op := r.begin(ctx, "ScheduleJob", attribute.String("job.name", job.Name))
+	defer op.end(&err)
+	_, err = r.jobsColl.Doc(job.Name).Set(ctx, job)
+	return err
+}
+
+// CancelJob removes a job that has not run yet. Jobs that have run are
+// kept for their history.
+func (r *Repository) CancelJob(ctx context.Context, name string) (err error) {
+	ctx, op := r.begin(ctx, "CancelJob", attribute.String("job.name", name))
+	defer op.end(&err)
+	ref := r.jobsColl.Doc(name)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		job, err := jobFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		if job.Runs > 0 {
+			return nil
+		}
+		return tx.Delete(ref)
+	})
+}
+
+// TriggerJob makes a job due now, or returns ErrNotFound
+func (r *Repository) TriggerJob(ctx context.Context, name string) (err error) {
+	ctx, op := r.begin(ctx, "TriggerJob", attribute.String("job.name", name))
+	defer op.end(&err)
+	_, err = r.jobsColl.Doc(name).Update(ctx, []firestore.Update{
+		{Path: "NextRunAt", Value: time.Now()},
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// StartJob moves the next run of a due job to next, or clears it for a
+// one-off job when next is nil, before the job runs, so the run is not
+// started again. It reports false if the job is no longer due, e.g.
+// because it was started elsewhere.
+func (r *Repository) StartJob(ctx context.Context, name string, next *time.Time) (started bool, err error) {
+	ctx, op := r.begin(ctx, "StartJob", attribute.String("job.name", name))
+	defer op.end(&err)
+	ref := r.jobsColl.Doc(name)
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		started = false
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		job, err := jobFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		if job.NextRunAt == nil || job.NextRunAt.After(time.Now()) {
+			return nil
+		}
+
+		started = true
+		var value interface{}
+		if next != nil {
+			value = *next
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "NextRunAt", Value: value}})
+	})
+	return started, err
+}
+
+// RecordJobRun stores the outcome of a run that started at startedAt
+func (r *Repository) RecordJobRun(ctx context.Context, name string, startedAt time.Time, duration time.Duration, runErr error) (err error) {
+	ctx, op := r.begin(ctx, "RecordJobRun", attribute.String("job.name", name))
+	defer op.end(&err)
+	outcome, message := models.JobSucceeded, ""
+	if runErr != nil {
+		outcome, message = models.JobFailed, runErr.Error()
+	}
+	_, err = r.jobsColl.Doc(name).Update(ctx, []firestore.Update{
+		{Path: "LastRunAt", Value: startedAt},
+		{Path: "LastDuration", Value: duration.Seconds()},
+		{Path: "LastStatus", Value: outcome},
+		{Path: "LastError", Value: message},
+		{Path: "Runs", Value: firestore.Increment(1)},
+	})
+	if status.Code(err) == codes.NotFound {
+		// A one-off job cancelled while it ran
+		return nil
+	}
+	return err
+}
+
+// PurgeFinishedJobs deletes one-off jobs that last ran before cutoff and
+// returns how many were deleted
+func (r *Repository) PurgeFinishedJobs(ctx context.Context, cutoff time.Time) (_ int, err error) {
+	ctx, op := r.begin(ctx, "PurgeFinishedJobs")
+	defer op.end(&err)
+	docs, err := r.jobsColl.Where("LastRunAt", "<", cutoff).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, doc := range docs {
+		job, err := jobFromDoc(doc)
+		if err != nil || job.Schedule != "" || job.NextRunAt != nil {
+			continue
+		}
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// PurgeIdempotencyKeys deletes idempotency records that expired before
+// cutoff and returns how many were deleted
+func (r *Repository) PurgeIdempotencyKeys(ctx context.Context, cutoff time.Time) (_ int, err error) {
+	ctx, op := r.begin(ctx, "PurgeIdempotencyKeys")
+	defer op.end(&err)
+	docs, err := r.idempotencyColl.Where("ExpiresAt", "<", cutoff).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, doc := range docs {
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return i, err
+		}
+	}
+	return len(docs), nil
+}
+
+// SaveAnalyticsSnapshot stores the snapshot of its date, replacing an
+// earlier one taken the same day
+func (r *Repository) SaveAnalyticsSnapshot(ctx context.Context, snapshot *models.AnalyticsSnapshot) (err error) {
+	ctx, op := r.begin(ctx, "SaveAnalyticsSnapshot")
+	defer op.end(&err)
+	_, err = r.snapshotsColl.Doc(snapshot.Date).Set(ctx, snapshot)
+	return err
+}
+
+// GetAnalyticsSnapshots returns every analytics snapshot, oldest first
+func (r *Repository) GetAnalyticsSnapshots(ctx context.Context) (_ []models.AnalyticsSnapshot, err error) {
+	ctx, op := r.begin(ctx, "GetAnalyticsSnapshots")
+	defer op.end(&err)
+	docs, err := r.snapshotsColl.OrderBy(firestore.DocumentID, firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]models.AnalyticsSnapshot, 0, len(docs))
+	for _, doc := range docs {
+		var snapshot models.AnalyticsSnapshot
+		if err := doc.DataTo(&snapshot); err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func jobFromDoc(doc *firestore.DocumentSnapshot) (*models.Job, error) {
+	var job models.Job
+	if err := doc.DataTo(&job); err != nil {
+		return nil, err
+	}
+	job.Name = doc.Ref.ID
+	return &job, nil
+}
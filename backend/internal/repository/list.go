@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PageQuery selects one page of a collection ordered by a single field,
+// with document IDs breaking ties. Only the page is read, and it needs no
+// more than the field's single-field index.
+type PageQuery struct {
+	// OrderBy is the stored field to order by, e.g. "RegisteredAt"
+	OrderBy string
+	Desc    bool
+	// AfterValue and AfterID are the sort value and ID of the last item of
+	// the previous page; AfterID is empty on the first page
+	AfterValue interface{}
+	AfterID    string
+	Limit      int
+	// From and To bound OrderBy to [From, To) when set
+	From, To *time.Time
+}
+
+// Page is one page of a collection, with the number of items matching
+// the query on every page
+type Page[T any] struct {
+	Items []T
+	Total int
+	More  bool
+}
+
+// ListAttendees returns one page of attendees
+func (r *Repository) ListAttendees(ctx context.Context, q PageQuery) (_ *Page[models.Attendee], err error) {
+	ctx, op := r.begin(ctx, "ListAttendees", attribute.String("list.order_by", q.OrderBy))
+	defer op.end(&err)
+	return listPage(ctx, r, r.attendeesColl, q, func(doc *firestore.DocumentSnapshot, a *models.Attendee) { a.ID = doc.Ref.ID })
+}
+
+// ListSpeakers returns one page of speakers
+func (r *Repository) ListSpeakers(ctx context.Context, q PageQuery) (_ *Page[models.Speaker], err error) {
+	ctx, op := r.begin(ctx, "ListSpeakers", attribute.String("list.order_by", q.OrderBy))
+	defer op.end(&err)
+	return listPage(ctx, r, r.speakersColl, q, func(doc *firestore.DocumentSnapshot, s *models.Speaker) { s.ID = doc.Ref.ID })
+}
+
+// ListSessionsWithSpeakers returns one page of sessions, reading only the
+// speakers of the sessions on the page
+func (r *Repository) ListSessionsWithSpeakers(ctx context.Context, q PageQuery) (_ *Page[models.SessionWithSpeakers], err error) {
+	ctx, op := r.begin(ctx, "ListSessionsWithSpeakers", attribute.String("list.order_by", q.OrderBy))
+	defer op.end(&err)
+	sessions, err := listPage(ctx, r, r.sessionsColl, q, func(doc *firestore.DocumentSnapshot, s *models.Session) { s.ID = doc.Ref.ID })
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []*firestore.DocumentRef
+	seen := make(map[string]bool)
+	for _, session := range sessions.Items {
+		for _, id := range session.SpeakerIDs {
+			if !seen[id] {
+				seen[id] = true
+				refs = append(refs, r.speakersColl.Doc(id))
+			}
+		}
+	}
+	speakers := make(map[string]models.Speaker, len(refs))
+	if len(refs) > 0 {
+		docs, err := r.client.GetAll(ctx, refs)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			var speaker models.Speaker
+			if !doc.Exists() || doc.DataTo(&speaker) != nil {
+				continue
+			}
+			speaker.ID = doc.Ref.ID
+			speakers[speaker.ID] = speaker
+		}
+	}
+
+	page := &Page[models.SessionWithSpeakers]{
+		Items: make([]models.SessionWithSpeakers, 0, len(sessions.Items)),
+		Total: sessions.Total,
+		More:  sessions.More,
+	}
+	for _, session := range sessions.Items {
+		joined := models.SessionWithSpeakers{Session: session, Speakers: make([]models.Speaker, 0, len(session.SpeakerIDs))}
+		for _, id := range session.SpeakerIDs {
+			if speaker, ok := speakers[id]; ok {
+				joined.Speakers = append(joined.Speakers, speaker)
+			}
+		}
+		page.Items = append(page.Items, joined)
+	}
+	return page, nil
+}
+
+// listPage runs q against coll, decoding each document into a T and
+// passing it to setID
+func listPage[T any](ctx context.Context, r *Repository, coll *firestore.CollectionRef, q PageQuery, setID func(*firestore.DocumentSnapshot, *T)) (*Page[T], error) {
+	query := coll.Query
+	if q.From != nil {
+		query = query.Where(q.OrderBy, ">=", *q.From)
+	}
+	if q.To != nil {
+		query = query.Where(q.OrderBy, "<", *q.To)
+	}
+	total, err := r.count(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := firestore.Asc
+	if q.Desc {
+		dir = firestore.Desc
+	}
+	query = query.OrderBy(q.OrderBy, dir).OrderBy(firestore.DocumentID, dir)
+	if q.AfterID != "" {
+		query = query.StartAfter(q.AfterValue, q.AfterID)
+	}
+	docs, err := query.Limit(q.Limit + 1).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: make([]T, 0, min(len(docs), q.Limit)), Total: total}
+	if len(docs) > q.Limit {
+		docs, page.More = docs[:q.Limit], true
+	}
+	for _, doc := range docs {
+		var item T
+		if err := doc.DataTo(&item); err != nil {
+			return nil, err
+		}
+		setID(doc, &item)
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// count counts the documents matching query with an aggregation query,
+// falling back to reading their keys where aggregation queries are
+// unavailable (e.g. older emulators)
+func (r *Repository) count(ctx context.Context, query firestore.Query) (int, error) {
+	result, err := query.NewAggregationQuery().WithCount("count").Get(ctx)
+	if status.Code(err) == codes.Unimplemented {
+		refs, err := query.Select().Documents(ctx).GetAll()
+		return len(refs), err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	count, ok := result["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count aggregation result %T", result["count"])
+	}
+	return int(count.GetIntegerValue()), nil
+}
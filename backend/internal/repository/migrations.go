@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// migration is a one-off change to an event's stored data. Migrations run
+// in order and each is recorded in the event's migrations collection once
+// it has succeeded, so running them again only applies new ones. Up must be
+// safe to re-run if it fails halfway.
+type migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, r *Repository) error
+}
+
+var migrations = []migration{
+	{
+		ID:          "0001-entity-versions",
+		Description: "Set version 1 on speakers and sessions stored before versioning",
+		Up: func(ctx context.Context, r *Repository) error {
+			for _, coll := range []*firestore.CollectionRef{r.speakersColl, r.sessionsColl} {
+				docs, err := coll.Documents(ctx).GetAll()
+				if err != nil {
+					return err
+				}
+				for _, doc := range docs {
+					if v, err := doc.DataAt("Version"); err == nil {
+						if version, _ := v.(int64); version > 0 {
+							continue
+						}
+					}
+					if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "Version", Value: 1}}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		ID:          "0002-attendee-counters",
+		Description: "Build the sharded attendee counters from the attendee records",
+		Up: func(ctx context.Context, r *Repository) error {
+			_, err := r.recomputeCounters(ctx)
+			return err
+		},
+	},
+	{
+		ID:          "0003-attendee-emails",
+		Description: "Lower-case attendee emails stored before emails were normalized",
+		Up: func(ctx context.Context, r *Repository) error {
+			docs, err := r.attendeesColl.Documents(ctx).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				email, err := doc.DataAt("Email")
+				if err != nil {
+					continue
+				}
+				s, _ := email.(string)
+				if normalized := normalizeEmail(s); normalized != s {
+					if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "Email", Value: normalized}}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Firestore leaves documents without the ordered field out of
+		// ordered queries, so the admin session list, which orders by
+		// StartsAt, would skip these sessions
+		ID:          "0004-session-schedule",
+		Description: "Store an empty schedule on sessions stored before StartsAt and EndsAt existed",
+		Up: func(ctx context.Context, r *Repository) error {
+			docs, err := r.sessionsColl.Documents(ctx).GetAll()
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				if updates := missingFields(doc.Data(), "StartsAt", "EndsAt"); len(updates) > 0 {
+					if _, err := doc.Ref.Update(ctx, updates); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// missingFields returns the updates that store null in each of fields that
+// data does not have
+func missingFields(data map[string]interface{}, fields ...string) []firestore.Update {
+	var updates []firestore.Update
+	for _, field := range fields {
+		if _, ok := data[field]; !ok {
+			updates = append(updates, firestore.Update{Path: field, Value: nil})
+		}
+	}
+	return updates
+}
+
+type migrationRecord struct {
+	AppliedAt time.Time
+}
+
+// GetMigrations returns every known migration and when it was applied to
+// this event
+func (r *Repository) GetMigrations(ctx context.Context) (_ []models.MigrationStatus, err error) {
+	ctx, op := r.begin(ctx, "GetMigrations")
+	defer op.end(&err)
+	statuses := make([]models.MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		migrationStatus := models.MigrationStatus{ID: m.ID, Description: m.Description}
+		doc, err := r.migrationsColl.Doc(m.ID).Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+		if err == nil {
+			var record migrationRecord
+			if err := doc.DataTo(&record); err != nil {
+				return nil, err
+			}
+			migrationStatus.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, migrationStatus)
+	}
+	return statuses, nil
+}
+
+// RunMigrations applies the migrations that have not run for this event yet,
+// in order, and returns the ones it applied. It stops at the first failure.
+func (r *Repository) RunMigrations(ctx context.Context) (_ []models.MigrationStatus, err error) {
+	ctx, op := r.begin(ctx, "RunMigrations")
+	defer op.end(&err)
+	statuses, err := r.GetMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []models.MigrationStatus
+	for i, m := range migrations {
+		if statuses[i].AppliedAt != nil {
+			continue
+		}
+		if err := m.Up(ctx, r); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.ID, err)
+		}
+		record := migrationRecord{AppliedAt: time.Now()}
+		if _, err := r.migrationsColl.Doc(m.ID).Set(ctx, record); err != nil {
+			return applied, err
+		}
+		statuses[i].AppliedAt = &record.AppliedAt
+		applied = append(applied, statuses[i])
+	}
+	return applied, nil
+}
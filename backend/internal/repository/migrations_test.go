@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+func TestMissingFields(t *testing.T) {
+	legacy := map[string]interface{}{"Title": "Keynote", "Time": "9:00 AM"}
+	updates := missingFields(legacy, "StartsAt", "EndsAt")
+	if len(updates) != 2 || updates[0].Path != "StartsAt" || updates[1].Path != "EndsAt" {
+		t.Fatalf("updates = %+v, want StartsAt and EndsAt", updates)
+	}
+	for _, u := range updates {
+		if u.Value != nil {
+			t.Errorf("%s = %v, want null", u.Path, u.Value)
+		}
+	}
+
+	scheduled := map[string]interface{}{"Title": "Keynote", "StartsAt": nil, "EndsAt": time.Now()}
+	if updates := missingFields(scheduled, "StartsAt", "EndsAt"); len(updates) != 0 {
+		t.Errorf("updates = %+v for a session with a schedule, want none", updates)
+	}
+}
+
+// emulatorRepository returns a repository for a fresh event in the
+// Firestore emulator, skipping the test when FIRESTORE_EMULATOR_HOST is unset
+func emulatorRepository(t *testing.T) *Repository {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	client, err := firestore.NewClient(context.Background(), "workshop-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return newEventRepository(client, "test-"+time.Now().Format("20060102150405.000000000"))
+}
+
+func TestLegacySessionListedAfterMigration(t *testing.T) {
+	r := emulatorRepository(t)
+	ctx := context.Background()
+
+	// A session stored before StartsAt and EndsAt existed
+	if _, err := r.sessionsColl.Doc("legacy").Set(ctx, map[string]interface{}{
+		"Title": "Keynote", "Time": "9:00 AM", "Version": 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	startsAt := time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC)
+	if _, err := r.sessionsColl.Doc("scheduled").Set(ctx, map[string]interface{}{
+		"Title": "Workshop", "StartsAt": startsAt, "EndsAt": startsAt.Add(time.Hour), "Version": 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.RunMigrations(ctx); err != nil {
+		t.Fatal(err)
+	}
+	page, err := r.ListSessionsWithSpeakers(ctx, PageQuery{OrderBy: "StartsAt", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Items) != 2 {
+		t.Fatalf("page has %d of %d sessions, want 2 of 2", len(page.Items), page.Total)
+	}
+	// Unset start times sort first
+	if page.Items[0].ID != "legacy" || page.Items[0].StartsAt != nil {
+		t.Errorf("first session = %s starting %v, want legacy without a start", page.Items[0].ID, page.Items[0].StartsAt)
+	}
+}
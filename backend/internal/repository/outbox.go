@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Outbox operations. Every change to attendees, speakers and sessions
+// writes an event to the outbox in its own transaction, so the event is
+// stored if and only if the change is. The outbox is not exported in
+// event archives.
+
+// addOutboxEvent writes an event of eventType with data within tx
+func (r *Repository) addOutboxEvent(tx *firestore.Transaction, eventType string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	event := models.OutboxEvent{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		Data:        string(body),
+		CreatedAt:   now,
+		LeasedUntil: now,
+	}
+	return tx.Create(r.outboxColl.Doc(event.ID), &event)
+}
+
+// deletedEvent is the data of a deletion event
+func deletedEvent(id string) map[string]string {
+	return map[string]string{"id": id}
+}
+
+// ClaimOutboxEvents returns up to limit events whose lease has expired,
+// oldest lease first, each leased to the caller for lease and with the
+// attempt counted. An event that is not completed before its lease ends
+// is claimed again, so events are handled at least once.
+func (r *Repository) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) (_ []models.OutboxEvent, err error) {
+	ctx, op := r.begin(ctx, "ClaimOutboxEvents")
+	defer op.end(&err)
+	docs, err := r.outboxColl.Where("LeasedUntil", "<=", time.Now()).
+		OrderBy("LeasedUntil", firestore.Asc).Limit(limit).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]models.OutboxEvent, 0, len(docs))
+	for _, doc := range docs {
+		ref := doc.Ref
+		var event *models.OutboxEvent
+		err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			event = nil
+			doc, err := tx.Get(ref)
+			if status.Code(err) == codes.NotFound {
+				// Completed by another instance since the query
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			current, err := outboxEventFromDoc(doc)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			if current.LeasedUntil.After(now) {
+				return nil
+			}
+
+			current.Attempts++
+			current.LeasedUntil = now.Add(lease)
+			event = current
+			return tx.Update(ref, []firestore.Update{
+				{Path: "Attempts", Value: current.Attempts},
+				{Path: "LeasedUntil", Value: current.LeasedUntil},
+			})
+		})
+		if err != nil {
+			return claimed, err
+		}
+		if event != nil {
+			claimed = append(claimed, *event)
+		}
+	}
+	return claimed, nil
+}
+
+// CompleteOutboxEvent removes a handled event
+func (r *Repository) CompleteOutboxEvent(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "CompleteOutboxEvent", attribute.String("outbox.event_id", id))
+	defer op.end(&err)
+	_, err = r.outboxColl.Doc(id).Delete(ctx)
+	return err
+}
+
+// RetryOutboxEvent records why an event failed and leases it until retryAt,
+// when it is claimed again
+func (r *Repository) RetryOutboxEvent(ctx context.Context, id string, retryAt time.Time, reason string) (err error) {
+	ctx, op := r.begin(ctx, "RetryOutboxEvent", attribute.String("outbox.event_id", id))
+	defer op.end(&err)
+	_, err = r.outboxColl.Doc(id).Update(ctx, []firestore.Update{
+		{Path: "LeasedUntil", Value: retryAt},
+		{Path: "Error", Value: reason},
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeadLetterOutboxEvent moves an event that keeps failing out of the
+// outbox into outboxDeadLetters, recording why, so it is not claimed again
+func (r *Repository) DeadLetterOutboxEvent(ctx context.Context, id, reason string) (err error) {
+	ctx, op := r.begin(ctx, "DeadLetterOutboxEvent", attribute.String("outbox.event_id", id))
+	defer op.end(&err)
+	ref := r.outboxColl.Doc(id)
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		event, err := outboxEventFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		event.Error = reason
+		event.DeadAt = &now
+		if err := tx.Set(r.deadLettersColl.Doc(id), event); err != nil {
+			return err
+		}
+		return tx.Delete(ref)
+	})
+}
+
+func outboxEventFromDoc(doc *firestore.DocumentSnapshot) (*models.OutboxEvent, error) {
+	var event models.OutboxEvent
+	if err := doc.DataTo(&event); err != nil {
+		return nil, err
+	}
+	event.ID = doc.Ref.ID
+	return &event, nil
+}
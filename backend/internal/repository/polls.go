@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrPollNotOpen = &kindError{"poll is not open", ErrConflict}
+
+// Poll operations
+func (r *Repository) CreatePoll(ctx context.Context, poll *models.Poll) (err error) {
+	ctx, op := r.begin(ctx, "CreatePoll", attribute.String("poll.id", poll.ID), attribute.String("session.id", poll.SessionID))
+	defer op.end(&err)
+	_, err = r.pollsColl.Doc(poll.ID).Set(ctx, poll)
+	return err
+}
+
+// GetPoll returns a poll or ErrNotFound
+func (r *Repository) GetPoll(ctx context.Context, id string) (_ *models.Poll, err error) {
+	ctx, op := r.begin(ctx, "GetPoll", attribute.String("poll.id", id))
+	defer op.end(&err)
+	doc, err := r.pollsColl.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pollFromDoc(doc)
+}
+
+// GetSessionPolls returns the polls of a session, oldest first. Drafts are
+// only included when includeDrafts is set.
+func (r *Repository) GetSessionPolls(ctx context.Context, sessionID string, includeDrafts bool) (_ []models.Poll, err error) {
+	ctx, op := r.begin(ctx, "GetSessionPolls", attribute.String("session.id", sessionID))
+	defer op.end(&err)
+	docs, err := r.pollsColl.Where("SessionID", "==", sessionID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return pollsFromDocs(docs, includeDrafts), nil
+}
+
+// GetAllPolls returns every poll with its results, oldest first
+func (r *Repository) GetAllPolls(ctx context.Context) (_ []models.Poll, err error) {
+	ctx, op := r.begin(ctx, "GetAllPolls")
+	defer op.end(&err)
+	docs, err := r.pollsColl.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return pollsFromDocs(docs, true), nil
+}
+
+// SetPollStatus opens or closes a poll, recording when it happened
+func (r *Repository) SetPollStatus(ctx context.Context, id, pollStatus string) (err error) {
+	ctx, op := r.begin(ctx, "SetPollStatus", attribute.String("poll.id", id))
+	defer op.end(&err)
+	updates := []firestore.Update{{Path: "Status", Value: pollStatus}}
+	switch pollStatus {
+	case models.PollOpen:
+		updates = append(updates,
+			firestore.Update{Path: "OpenedAt", Value: time.Now()},
+			firestore.Update{Path: "ClosedAt", Value: nil},
+		)
+	case models.PollClosed:
+		updates = append(updates, firestore.Update{Path: "ClosedAt", Value: time.Now()})
+	}
+
+	_, err = r.pollsColl.Doc(id).Update(ctx, updates)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (r *Repository) DeletePoll(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "DeletePoll", attribute.String("poll.id", id))
+	defer op.end(&err)
+	_, err = r.pollsColl.Doc(id).Delete(ctx)
+	return err
+}
+
+// VotePoll records an attendee's vote and increments the chosen options in
+// one transaction. It returns ErrAlreadyExists if the attendee has already
+// voted and ErrPollNotOpen unless the poll is open.
+func (r *Repository) VotePoll(ctx context.Context, pollID, attendeeID string, optionIDs []string) (err error) {
+	ctx, op := r.begin(ctx, "VotePoll", attribute.String("poll.id", pollID), attribute.String("attendee.id", attendeeID))
+	defer op.end(&err)
+	pollRef := r.pollsColl.Doc(pollID)
+	voteRef := pollRef.Collection("votes").Doc(attendeeID)
+
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(pollRef)
+		if err != nil {
+			return err
+		}
+		var poll models.Poll
+		if err := doc.DataTo(&poll); err != nil {
+			return err
+		}
+		if poll.Status != models.PollOpen {
+			return ErrPollNotOpen
+		}
+
+		vote, err := tx.Get(voteRef)
+		if err == nil && vote.Exists() {
+			return ErrAlreadyExists
+		}
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if err := tx.Create(voteRef, map[string]interface{}{
+			"OptionIDs": optionIDs,
+			"VotedAt":   time.Now(),
+		}); err != nil {
+			return err
+		}
+		updates := []firestore.Update{{Path: "Voters", Value: firestore.Increment(1)}}
+		for _, id := range optionIDs {
+			updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{"Votes", id}, Value: firestore.Increment(1)})
+		}
+		return tx.Update(pollRef, updates)
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// GetPollResults returns the results of every opened poll grouped by
+// session, for post-event analytics
+func (r *Repository) GetPollResults(ctx context.Context) (_ []models.SessionPollResults, err error) {
+	ctx, op := r.begin(ctx, "GetPollResults")
+	defer op.end(&err)
+	polls, err := r.GetAllPolls(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := r.GetAllSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bySession := make(map[string][]models.Poll)
+	for _, poll := range polls {
+		if poll.Status != models.PollDraft {
+			bySession[poll.SessionID] = append(bySession[poll.SessionID], poll)
+		}
+	}
+
+	results := make([]models.SessionPollResults, 0, len(bySession))
+	for _, session := range sessions {
+		if len(bySession[session.ID]) == 0 {
+			continue
+		}
+		results = append(results, models.SessionPollResults{
+			SessionID:    session.ID,
+			SessionTitle: session.Title,
+			Polls:        bySession[session.ID],
+		})
+	}
+	return results, nil
+}
+
+func pollFromDoc(doc *firestore.DocumentSnapshot) (*models.Poll, error) {
+	var poll models.Poll
+	if err := doc.DataTo(&poll); err != nil {
+		return nil, err
+	}
+	poll.ID = doc.Ref.ID
+	if poll.Votes == nil {
+		poll.Votes = make(map[string]int)
+	}
+	return &poll, nil
+}
+
+func pollsFromDocs(docs []*firestore.DocumentSnapshot, includeDrafts bool) []models.Poll {
+	polls := make([]models.Poll, 0, len(docs))
+	for _, doc := range docs {
+		poll, err := pollFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		if poll.Status == models.PollDraft && !includeDrafts {
+			continue
+		}
+		polls = append(polls, *poll)
+	}
+
+	sort.Slice(polls, func(i, j int) bool {
+		return polls[i].CreatedAt.Before(polls[j].CreatedAt)
+	})
+	return polls
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Question operations
+func (r *Repository) CreateQuestion(ctx context.Context, question *models.Question) (err error) {
+	ctx, op := r.begin(ctx, "CreateQuestion", attribute.String("question.id", question.ID), attribute.String("session.id", question.SessionID))
+	defer op.end(&err)
+	_, err = r.questionsColl.Doc(question.ID).Set(ctx, question)
+	return err
+}
+
+// GetQuestion returns a question or ErrNotFound
+func (r *Repository) GetQuestion(ctx context.Context, id string) (_ *models.Question, err error) {
+	ctx, op := r.begin(ctx, "GetQuestion", attribute.String("question.id", id))
+	defer op.end(&err)
+	doc, err := r.questionsColl.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var question models.Question
+	if err := doc.DataTo(&question); err != nil {
+		return nil, err
+	}
+	question.ID = doc.Ref.ID
+	return &question, nil
+}
+
+// GetSessionQuestions returns the questions of a session in display order:
+// pinned first, then open questions by votes, then answered questions.
+// Hidden questions are only included when includeHidden is set.
+func (r *Repository) GetSessionQuestions(ctx context.Context, sessionID string, includeHidden bool) (_ []models.Question, err error) {
+	ctx, op := r.begin(ctx, "GetSessionQuestions", attribute.String("session.id", sessionID))
+	defer op.end(&err)
+	docs, err := r.questionsColl.Where("SessionID", "==", sessionID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	questions := make([]models.Question, 0, len(docs))
+	for _, doc := range docs {
+		var question models.Question
+		if err := doc.DataTo(&question); err != nil {
+			continue
+		}
+		if question.Hidden && !includeHidden {
+			continue
+		}
+		question.ID = doc.Ref.ID
+		questions = append(questions, question)
+	}
+
+	sort.SliceStable(questions, func(i, j int) bool {
+		a, b := questions[i], questions[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		if a.Answered != b.Answered {
+			return !a.Answered
+		}
+		if a.Upvotes != b.Upvotes {
+			return a.Upvotes > b.Upvotes
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return questions, nil
+}
+
+// UpvoteQuestion records an attendee's vote and increments the question's
+// count in one transaction. It returns ErrAlreadyExists if the attendee
+// has already voted and ErrNotFound if the question does not exist.
+func (r *Repository) UpvoteQuestion(ctx context.Context, questionID, attendeeID string) (err error) {
+	ctx, op := r.begin(ctx, "UpvoteQuestion", attribute.String("question.id", questionID), attribute.String("attendee.id", attendeeID))
+	defer op.end(&err)
+	questionRef := r.questionsColl.Doc(questionID)
+	voteRef := questionRef.Collection("upvotes").Doc(attendeeID)
+
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(questionRef); err != nil {
+			return err
+		}
+		vote, err := tx.Get(voteRef)
+		if err == nil && vote.Exists() {
+			return ErrAlreadyExists
+		}
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if err := tx.Create(voteRef, map[string]interface{}{"VotedAt": time.Now()}); err != nil {
+			return err
+		}
+		return tx.Update(questionRef, []firestore.Update{{Path: "Upvotes", Value: firestore.Increment(1)}})
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// ModerateQuestion applies the set fields of moderation to a question
+func (r *Repository) ModerateQuestion(ctx context.Context, id string, moderation models.QuestionModeration) (err error) {
+	ctx, op := r.begin(ctx, "ModerateQuestion", attribute.String("question.id", id))
+	defer op.end(&err)
+	var updates []firestore.Update
+	if moderation.Hidden != nil {
+		updates = append(updates, firestore.Update{Path: "Hidden", Value: *moderation.Hidden})
+	}
+	if moderation.Pinned != nil {
+		updates = append(updates, firestore.Update{Path: "Pinned", Value: *moderation.Pinned})
+	}
+	if moderation.Answered != nil {
+		var answeredAt *time.Time
+		if *moderation.Answered {
+			now := time.Now()
+			answeredAt = &now
+		}
+		updates = append(updates,
+			firestore.Update{Path: "Answered", Value: *moderation.Answered},
+			firestore.Update{Path: "AnsweredAt", Value: answeredAt},
+		)
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	_, err = r.questionsColl.Doc(id).Update(ctx, updates)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Kinds of entities that can be moved to the trash
+const (
+	KindAttendees = "attendees"
+	KindSpeakers  = "speakers"
+	KindSessions  = "sessions"
+)
+
+// trashMeta holds the fields added to an entity's data when it is moved to
+// the trash. Trashed documents keep every other field of the entity.
+type trashMeta struct {
+	Kind      string
+	EntityID  string
+	DeletedAt time.Time
+}
+
+func (r *Repository) kindCollection(kind string) (*firestore.CollectionRef, error) {
+	switch kind {
+	case KindAttendees:
+		return r.attendeesColl, nil
+	case KindSpeakers:
+		return r.speakersColl, nil
+	case KindSessions:
+		return r.sessionsColl, nil
+	}
+	return nil, fmt.Errorf("%w: unknown kind %q", ErrValidation, kind)
+}
+
+func (r *Repository) trashRef(kind, id string) *firestore.DocumentRef {
+	return r.trashColl.Doc(kind + "_" + id)
+}
+
+// moveToTrash soft-deletes the document with id in one transaction. Before
+// the move, onTrash may add writes for the entity, e.g. counter updates.
+// It returns ErrNotFound if there is no such document.
+func (r *Repository) moveToTrash(ctx context.Context, kind, id string, onTrash func(*firestore.Transaction, *firestore.DocumentSnapshot) error) error {
+	coll, err := r.kindCollection(kind)
+	if err != nil {
+		return err
+	}
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(coll.Doc(id))
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if onTrash != nil {
+			if err := onTrash(tx, doc); err != nil {
+				return err
+			}
+		}
+
+		data := doc.Data()
+		data["Kind"] = kind
+		data["EntityID"] = id
+		data["DeletedAt"] = time.Now()
+		if err := tx.Set(r.trashRef(kind, id), data); err != nil {
+			return err
+		}
+		return tx.Delete(doc.Ref)
+	})
+}
+
+// GetTrash returns the trashed entities of a kind, most recently deleted
+// first
+func (r *Repository) GetTrash(ctx context.Context, kind string) (_ []models.TrashItem, err error) {
+	ctx, op := r.begin(ctx, "GetTrash", attribute.String("trash.kind", kind))
+	defer op.end(&err)
+	if _, err := r.kindCollection(kind); err != nil {
+		return nil, err
+	}
+	docs, err := r.trashColl.Where("Kind", "==", kind).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]models.TrashItem, 0, len(docs))
+	for _, doc := range docs {
+		item, err := trashItemFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+// restoredEvents are the outbox events written when an entity of each kind
+// is restored. They are the events of its creation, so that subscribers
+// and jobs pick it up again as if it were new.
+var restoredEvents = map[string]string{
+	KindAttendees: models.EventAttendeeRegistered,
+	KindSpeakers:  models.EventSpeakerCreated,
+	KindSessions:  models.EventSessionCreated,
+}
+
+// RestoreFromTrash moves a trashed entity back to its collection and writes
+// its creation event to the outbox. It returns ErrNotFound if it is not in
+// the trash and ErrAlreadyExists if an entity with the same ID exists again.
+func (r *Repository) RestoreFromTrash(ctx context.Context, kind, id string) (_ *models.TrashItem, err error) {
+	ctx, op := r.begin(ctx, "RestoreFromTrash", attribute.String("trash.kind", kind), attribute.String("trash.entity_id", id))
+	defer op.end(&err)
+	coll, err := r.kindCollection(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var item *models.TrashItem
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(r.trashRef(kind, id))
+		if status.Code(err) == codes.NotFound {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		live := coll.Doc(id)
+		if _, err := tx.Get(live); err == nil {
+			return ErrAlreadyExists
+		} else if status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if item, err = trashItemFromDoc(doc); err != nil {
+			return err
+		}
+
+		data := doc.Data()
+		delete(data, "Kind")
+		delete(data, "EntityID")
+		delete(data, "DeletedAt")
+		if err := tx.Set(live, data); err != nil {
+			return err
+		}
+		if err := tx.Delete(doc.Ref); err != nil {
+			return err
+		}
+		if attendee, ok := item.Item.(models.Attendee); ok {
+			if err := r.countAttendee(tx, attendee.Designation, 1); err != nil {
+				return err
+			}
+		}
+		return r.addOutboxEvent(tx, restoredEvents[kind], item.Item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// DeleteFromTrash permanently deletes a trashed entity and returns it. It
+// returns ErrNotFound if it is not in the trash and ErrConflict if it
+// changed while being deleted.
+func (r *Repository) DeleteFromTrash(ctx context.Context, kind, id string) (_ *models.TrashItem, err error) {
+	ctx, op := r.begin(ctx, "DeleteFromTrash", attribute.String("trash.kind", kind), attribute.String("trash.entity_id", id))
+	defer op.end(&err)
+	if _, err := r.kindCollection(kind); err != nil {
+		return nil, err
+	}
+	doc, err := r.trashRef(kind, id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := trashItemFromDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	// Fails if the entity was restored and deleted again meanwhile
+	_, err = doc.Ref.Delete(ctx, firestore.LastUpdateTime(doc.UpdateTime))
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, fmt.Errorf("%w: %w", ErrConflict, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// PurgeTrash permanently deletes every entity trashed before cutoff and
+// returns them. Each entity is deleted in a transaction that checks it is
+// still due, so one restored and deleted again meanwhile is kept.
+func (r *Repository) PurgeTrash(ctx context.Context, cutoff time.Time) (_ []models.TrashItem, err error) {
+	ctx, op := r.begin(ctx, "PurgeTrash")
+	defer op.end(&err)
+	refs, err := r.trashColl.Where("DeletedAt", "<", cutoff).Select().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	purged := make([]models.TrashItem, 0, len(refs))
+	for _, ref := range refs {
+		var item *models.TrashItem
+		err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			item = nil
+			doc, err := tx.Get(ref.Ref)
+			if status.Code(err) == codes.NotFound {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			var meta trashMeta
+			if err := doc.DataTo(&meta); err != nil {
+				return err
+			}
+			if !meta.DeletedAt.Before(cutoff) {
+				return nil
+			}
+			// Undecodable entities are purged all the same
+			item, _ = trashItemFromDoc(doc)
+			return tx.Delete(doc.Ref)
+		})
+		if err != nil {
+			return purged, err
+		}
+		if item != nil {
+			purged = append(purged, *item)
+		}
+	}
+	return purged, nil
+}
+
+// trashItemFromDoc decodes a trashed document into the entity of its kind
+func trashItemFromDoc(doc *firestore.DocumentSnapshot) (*models.TrashItem, error) {
+	var meta trashMeta
+	if err := doc.DataTo(&meta); err != nil {
+		return nil, err
+	}
+
+	item := &models.TrashItem{ID: meta.EntityID, Kind: meta.Kind, DeletedAt: meta.DeletedAt}
+	switch meta.Kind {
+	case KindAttendees:
+		var attendee models.Attendee
+		if err := doc.DataTo(&attendee); err != nil {
+			return nil, err
+		}
+		attendee.ID = meta.EntityID
+		item.Item = attendee
+	case KindSpeakers:
+		var speaker models.Speaker
+		if err := doc.DataTo(&speaker); err != nil {
+			return nil, err
+		}
+		speaker.ID = meta.EntityID
+		item.Item = speaker
+	case KindSessions:
+		var session models.Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, err
+		}
+		session.ID = meta.EntityID
+		item.Item = session
+	default:
+		return nil, fmt.Errorf("unknown kind %q", meta.Kind)
+	}
+	return item, nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Watches call onChange with every snapshot of a query, the first one
+// included, until ctx is cancelled. Changes are seen whichever instance
+// made them. They return nil once ctx is cancelled, or the error that
+// ended the listener.
+
+// WatchSessionQuestions watches the questions of a session, including
+// their upvote counts
+func (r *Repository) WatchSessionQuestions(ctx context.Context, sessionID string, onChange func()) error {
+	return watch(ctx, r.questionsColl.Where("SessionID", "==", sessionID), onChange)
+}
+
+// WatchSessionPolls watches the polls of a session, including their results
+func (r *Repository) WatchSessionPolls(ctx context.Context, sessionID string, onChange func()) error {
+	return watch(ctx, r.pollsColl.Where("SessionID", "==", sessionID), onChange)
+}
+
+func watch(ctx context.Context, q firestore.Query, onChange func()) error {
+	it := q.Snapshots(ctx)
+	defer it.Stop()
+	for {
+		if _, err := it.Next(); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		onChange()
+	}
+}
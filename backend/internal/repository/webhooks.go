@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"appdirect-workshop-backend/internal/models"
+
+	"cloud.google.com/go/firestore"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Webhook operations. Deliveries live in webhookDeliveries and are not
+// exported in event archives.
+
+// CreateWebhook stores a new webhook
+func (r *Repository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (err error) {
+	ctx, op := r.begin(ctx, "CreateWebhook", attribute.String("webhook.id", webhook.ID))
+	defer op.end(&err)
+	_, err = r.webhooksColl.Doc(webhook.ID).Create(ctx, webhook)
+	if status.Code(err) == codes.AlreadyExists {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetWebhook returns a webhook, including its secret, or ErrNotFound
+func (r *Repository) GetWebhook(ctx context.Context, id string) (_ *models.Webhook, err error) {
+	ctx, op := r.begin(ctx, "GetWebhook", attribute.String("webhook.id", id))
+	defer op.end(&err)
+	doc, err := r.webhooksColl.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhookFromDoc(doc)
+}
+
+// GetWebhooks returns every webhook, oldest first
+func (r *Repository) GetWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	ctx, op := r.begin(ctx, "GetWebhooks")
+	defer op.end(&err)
+	docs, err := r.webhooksColl.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	webhooks := make([]models.Webhook, 0, len(docs))
+	for _, doc := range docs {
+		webhook, err := webhookFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt) })
+	return webhooks, nil
+}
+
+// UpdateWebhook changes the URL, events and description of a webhook, or
+// returns ErrNotFound
+func (r *Repository) UpdateWebhook(ctx context.Context, id string, input *models.WebhookInput) (err error) {
+	ctx, op := r.begin(ctx, "UpdateWebhook", attribute.String("webhook.id", id))
+	defer op.end(&err)
+	_, err = r.webhooksColl.Doc(id).Update(ctx, []firestore.Update{
+		{Path: "URL", Value: input.URL},
+		{Path: "Events", Value: input.Events},
+		{Path: "Description", Value: input.Description},
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// SetWebhookActive enables or disables a webhook, or returns ErrNotFound.
+// Enabling clears the failure count, so the endpoint gets a fresh start.
+func (r *Repository) SetWebhookActive(ctx context.Context, id string, active bool, reason string) (err error) {
+	ctx, op := r.begin(ctx, "SetWebhookActive", attribute.String("webhook.id", id))
+	defer op.end(&err)
+	updates := []firestore.Update{{Path: "Active", Value: active}}
+	if active {
+		updates = append(updates,
+			firestore.Update{Path: "ConsecutiveFailures", Value: 0},
+			firestore.Update{Path: "FailingSince", Value: nil},
+			firestore.Update{Path: "DisabledAt", Value: nil},
+			firestore.Update{Path: "DisabledReason", Value: ""},
+		)
+	} else {
+		updates = append(updates,
+			firestore.Update{Path: "DisabledAt", Value: time.Now()},
+			firestore.Update{Path: "DisabledReason", Value: reason},
+		)
+	}
+
+	_, err = r.webhooksColl.Doc(id).Update(ctx, updates)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteWebhook removes a webhook, or returns ErrNotFound. Its delivery
+// log is kept; pending deliveries fail when they are next attempted.
+func (r *Repository) DeleteWebhook(ctx context.Context, id string) (err error) {
+	ctx, op := r.begin(ctx, "DeleteWebhook", attribute.String("webhook.id", id))
+	defer op.end(&err)
+	_, err = r.webhooksColl.Doc(id).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return ErrNotFound
+	}
+	return err
+}
+
+// CreateWebhookDeliveries stores new deliveries. Deliveries that already
+// exist are left as they are, so queuing an event again is harmless.
+func (r *Repository) CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) (err error) {
+	ctx, op := r.begin(ctx, "CreateWebhookDeliveries")
+	defer op.end(&err)
+	for i := range deliveries {
+		_, err := r.deliveriesColl.Doc(deliveries[i].ID).Create(ctx, &deliveries[i])
+		if err != nil && status.Code(err) != codes.AlreadyExists {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWebhookDelivery returns a delivery or ErrNotFound
+func (r *Repository) GetWebhookDelivery(ctx context.Context, id string) (_ *models.WebhookDelivery, err error) {
+	ctx, op := r.begin(ctx, "GetWebhookDelivery", attribute.String("webhook.delivery_id", id))
+	defer op.end(&err)
+	doc, err := r.deliveriesColl.Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deliveryFromDoc(doc)
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook
+func (r *Repository) GetWebhookDeliveries(ctx context.Context, webhookID string) (_ []models.WebhookDelivery, err error) {
+	ctx, op := r.begin(ctx, "GetWebhookDeliveries", attribute.String("webhook.id", webhookID))
+	defer op.end(&err)
+	docs, err := r.deliveriesColl.Where("WebhookID", "==", webhookID).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return deliveriesFromDocs(docs), nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+// due, oldest first. Each is claimed for lease by moving its next attempt
+// past the lease and counting the attempt, so other instances skip it
+// while it is being sent, and retry it if this instance never reports
+// back.
+func (r *Repository) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) (_ []models.WebhookDelivery, err error) {
+	ctx, op := r.begin(ctx, "ClaimWebhookDeliveries")
+	defer op.end(&err)
+	docs, err := r.deliveriesColl.Where("Status", "==", models.DeliveryPending).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	due := make([]models.WebhookDelivery, 0, len(docs))
+	for _, d := range deliveriesFromDocs(docs) {
+		if d.NextAttemptAt == nil || !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	claimed := make([]models.WebhookDelivery, 0, min(limit, len(due)))
+	for _, d := range due {
+		if len(claimed) == limit {
+			break
+		}
+		ref := r.deliveriesColl.Doc(d.ID)
+		var delivery *models.WebhookDelivery
+		err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			delivery = nil
+			doc, err := tx.Get(ref)
+			if err != nil {
+				return err
+			}
+			current, err := deliveryFromDoc(doc)
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			if current.Status != models.DeliveryPending || (current.NextAttemptAt != nil && current.NextAttemptAt.After(now)) {
+				return nil
+			}
+
+			next := now.Add(lease)
+			current.Attempts++
+			current.NextAttemptAt = &next
+			delivery = current
+			return tx.Update(ref, []firestore.Update{
+				{Path: "Attempts", Value: current.Attempts},
+				{Path: "NextAttemptAt", Value: next},
+			})
+		})
+		if err != nil {
+			return claimed, err
+		}
+		if delivery != nil {
+			claimed = append(claimed, *delivery)
+		}
+	}
+	return claimed, nil
+}
+
+// RecordWebhookAttempt stores the outcome of a delivery attempt and counts
+// it against the webhook. A webhook that has failed at least disableAfter
+// consecutive attempts over at least disablePeriod is disabled, in which
+// case disabled is true.
+func (r *Repository) RecordWebhookAttempt(ctx context.Context, delivery *models.WebhookDelivery, succeeded bool, disableAfter int, disablePeriod time.Duration) (disabled bool, err error) {
+	ctx, op := r.begin(ctx, "RecordWebhookAttempt", attribute.String("webhook.id", delivery.WebhookID), attribute.String("webhook.delivery_id", delivery.ID))
+	defer op.end(&err)
+	webhookRef := r.webhooksColl.Doc(delivery.WebhookID)
+	deliveryRef := r.deliveriesColl.Doc(delivery.ID)
+
+	err = r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		disabled = false
+		doc, err := tx.Get(webhookRef)
+		if status.Code(err) == codes.NotFound {
+			// The webhook was deleted; only the log is left to update
+			return tx.Set(deliveryRef, delivery)
+		}
+		if err != nil {
+			return err
+		}
+		webhook, err := webhookFromDoc(doc)
+		if err != nil {
+			return err
+		}
+
+		updates := []firestore.Update{
+			{Path: "ConsecutiveFailures", Value: 0},
+			{Path: "FailingSince", Value: nil},
+		}
+		if !succeeded {
+			now := time.Now()
+			failingSince := now
+			if webhook.FailingSince != nil {
+				failingSince = *webhook.FailingSince
+			}
+			failures := webhook.ConsecutiveFailures + 1
+			updates = []firestore.Update{
+				{Path: "ConsecutiveFailures", Value: failures},
+				{Path: "FailingSince", Value: failingSince},
+			}
+			if webhook.Active && failures >= disableAfter && now.Sub(failingSince) >= disablePeriod {
+				disabled = true
+				updates = append(updates,
+					firestore.Update{Path: "Active", Value: false},
+					firestore.Update{Path: "DisabledAt", Value: now},
+					firestore.Update{Path: "DisabledReason", Value: "Too many failed deliveries"},
+				)
+			}
+		}
+		if err := tx.Update(webhookRef, updates); err != nil {
+			return err
+		}
+		return tx.Set(deliveryRef, delivery)
+	})
+	return disabled, err
+}
+
+func webhookFromDoc(doc *firestore.DocumentSnapshot) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := doc.DataTo(&webhook); err != nil {
+		return nil, err
+	}
+	webhook.ID = doc.Ref.ID
+	return &webhook, nil
+}
+
+func deliveryFromDoc(doc *firestore.DocumentSnapshot) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := doc.DataTo(&delivery); err != nil {
+		return nil, err
+	}
+	delivery.ID = doc.Ref.ID
+	return &delivery, nil
+}
+
+// deliveriesFromDocs decodes deliveries, oldest first
+func deliveriesFromDocs(docs []*firestore.DocumentSnapshot) []models.WebhookDelivery {
+	deliveries := make([]models.WebhookDelivery, 0, len(docs))
+	for _, doc := range docs {
+		delivery, err := deliveryFromDoc(doc)
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+	return deliveries
+}
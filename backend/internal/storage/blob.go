@@ -0,0 +1,31 @@
+// Package storage provides blob storage for uploaded media
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// Blob is an open stored object. Callers must close it.
+type Blob struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// BlobStore stores opaque objects under slash-separated keys
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing object
+	Put(ctx context.Context, key, contentType string, r io.Reader) error
+	// Open returns the object stored under key or ErrBlobNotFound
+	Open(ctx context.Context, key string) (*Blob, error)
+	// DeletePrefix removes every object whose key starts with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+	// Ping checks that objects can be stored
+	Ping(ctx context.Context) error
+}
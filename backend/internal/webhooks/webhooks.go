@@ -0,0 +1,362 @@
+// Package webhooks delivers events to admin-configured HTTP endpoints.
+// Outbox events become one delivery per subscribed webhook, stored in
+// Firestore and sent as signed POST requests, with retries and exponential
+// backoff, by whichever instance claims them first.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"appdirect-workshop-backend/internal/logging"
+	"appdirect-workshop-backend/internal/models"
+	"appdirect-workshop-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// SignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256>" of
+	// "<unix time>.<body>", keyed with the webhook's secret
+	SignatureHeader = "X-Webhook-Signature"
+
+	// MaxAttempts is the number of attempts before a delivery fails
+	MaxAttempts = 8
+	// DisableAfter and DisablePeriod decide when an endpoint keeps failing:
+	// it is disabled once it has failed this many attempts in a row, over
+	// at least this long, without a success in between
+	DisableAfter  = 15
+	DisablePeriod = time.Hour
+
+	// retryBase is the delay before the second attempt; it doubles with
+	// each attempt up to retryMax
+	retryBase = 30 * time.Second
+	retryMax  = time.Hour
+
+	// requestTimeout bounds a delivery request, and deliveryLease how long
+	// a claimed delivery is hidden from other instances
+	requestTimeout = 10 * time.Second
+	deliveryLease  = time.Minute
+
+	// pollInterval is how often due retries are looked for, and
+	// claimBatch how many deliveries are claimed at a time
+	pollInterval = 15 * time.Second
+	claimBatch   = 20
+)
+
+// deliveryNamespace derives delivery IDs from event and webhook IDs
+var deliveryNamespace = uuid.MustParse("8f0c5a4e-3d1b-4c6e-9a7f-2b5d8e1f4c3a")
+
+// Dispatcher turns outbox events into deliveries and sends them
+type Dispatcher struct {
+	repo   *repository.Repository
+	client *http.Client
+	wake   chan struct{}
+}
+
+func NewDispatcher(repo *repository.Repository) *Dispatcher {
+	return &Dispatcher{
+		repo:   repo,
+		client: newClient(&net.Dialer{Timeout: requestTimeout, Control: checkDial}),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// newClient returns the client deliveries are sent with. It does not
+// follow redirects, so a 3xx response counts as a failed attempt, and
+// ignores proxy settings so every connection goes through dialer.
+func newClient(dialer *net.Dialer) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// CheckURL reports why raw cannot be a webhook endpoint: it must be an
+// absolute https URL and must not name an internal address
+func CheckURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("is not a valid URL")
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an https URL")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && internal(ip) {
+		return errors.New("must not be a loopback, private or link-local address")
+	}
+	return nil
+}
+
+// checkDial refuses connections to internal addresses, whatever the
+// endpoint's host name resolved to
+func checkDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || internal(ip) {
+		return fmt.Errorf("refusing to connect to internal address %s", host)
+	}
+	return nil
+}
+
+// internal reports whether ip is loopback, private, link-local (including
+// the cloud metadata server at 169.254.169.254), multicast or unspecified
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// Run sends due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Replay queues a new delivery of the event sent by delivery, to be sent
+// right away. It keeps the event ID so receivers can recognise it.
+func (d *Dispatcher) Replay(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	replay := models.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: delivery.WebhookID,
+		EventID:   delivery.EventID,
+		Event:     delivery.Event,
+		Payload:   delivery.Payload,
+		Status:    models.DeliveryPending,
+		ReplayOf:  delivery.ID,
+		CreatedAt: time.Now(),
+	}
+	if err := d.repo.CreateWebhookDeliveries(ctx, []models.WebhookDelivery{replay}); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return &replay, nil
+}
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value for body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue is the outbox handler that stores a delivery of event for every
+// active webhook subscribed to it. Delivery IDs are derived from the event
+// and webhook, so handling the same event again queues nothing new.
+func (d *Dispatcher) Enqueue(ctx context.Context, event *models.OutboxEvent) error {
+	webhooks, err := d.repo.GetWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := models.WebhookPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		Workshop:  d.repo.EventID(),
+		CreatedAt: event.CreatedAt,
+		Data:      json.RawMessage(event.Data),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, w := range webhooks {
+		if !w.Active || !slices.Contains(w.Events, event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:        uuid.NewSHA1(deliveryNamespace, []byte(event.ID+"/"+w.ID)).String(),
+			WebhookID: w.ID,
+			EventID:   payload.ID,
+			Event:     event.Type,
+			Payload:   string(body),
+			Status:    models.DeliveryPending,
+			CreatedAt: time.Now(),
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := d.repo.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// deliverDue sends the deliveries that are due, a batch at a time, so one
+// slow endpoint does not hold up the others
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.ClaimWebhookDeliveries(ctx, claimBatch, deliveryLease)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to claim webhook deliveries", "error", err)
+			return
+		}
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				d.attempt(ctx, delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+		if len(deliveries) < claimBatch {
+			return
+		}
+	}
+}
+
+// attempt sends a claimed delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("deliveryId", delivery.ID, "webhookId", delivery.WebhookID))
+	now := time.Now()
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+
+	webhook, err := d.repo.GetWebhook(ctx, delivery.WebhookID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		d.finish(ctx, delivery, false, "Webhook was deleted", true)
+		return
+	case err != nil:
+		// Leave the claim to lapse so the attempt is retried
+		logging.FromContext(ctx).Warn("Failed to load webhook", "error", err)
+		return
+	case !webhook.Active:
+		d.finish(ctx, delivery, false, "Webhook is disabled", true)
+		return
+	}
+	if err := CheckURL(webhook.URL); err != nil {
+		d.finish(ctx, delivery, false, "Webhook URL "+err.Error(), true)
+		return
+	}
+
+	status, err := d.send(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+	switch {
+	case err != nil:
+		d.finish(ctx, delivery, false, err.Error(), false)
+	case status < 200 || status > 299:
+		d.finish(ctx, delivery, false, fmt.Sprintf("Endpoint responded with %d", status), false)
+	default:
+		d.finish(ctx, delivery, true, "", false)
+	}
+}
+
+// send POSTs the delivery's payload and returns the response status
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "workshop-webhooks/1")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+// finish records an attempt. Failed attempts are retried with backoff
+// until MaxAttempts, unless final is set.
+func (d *Dispatcher) finish(ctx context.Context, delivery *models.WebhookDelivery, succeeded bool, reason string, final bool) {
+	delivery.Error = reason
+	switch {
+	case succeeded:
+		delivery.Status = models.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case final || delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(Backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	disabled, err := d.repo.RecordWebhookAttempt(ctx, delivery, succeeded, DisableAfter, DisablePeriod)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to record webhook delivery", "error", err)
+		return
+	}
+	if disabled {
+		logging.FromContext(ctx).Warn("Disabled webhook after repeated failures")
+	}
+}
+
+// Backoff returns the delay after the given number of failed attempts:
+// retryBase doubled for each attempt after the first, at most retryMax,
+// with up to 10% jitter so retries from a burst spread out
+func Backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, retryMax)
+	return delay + time.Duration(mrand.Int63n(int64(delay/10)+1))
+}
@@ -0,0 +1,121 @@
+package webhooks
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	got := Sign("whsec_test", time.Unix(1700000000, 0), []byte(`{"id":"evt_1","type":"session.updated"}`))
+	want := "t=1700000000,v1=936e5f97ec7ba33465c1f8496532a90a19ccec1b066bbda347af09cbe518d3fb"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 50; i++ {
+			got := Backoff(tt.attempts)
+			if got < tt.base || got > tt.base+tt.base/10 {
+				t.Fatalf("Backoff(%d) = %v, want %v plus at most 10%%", tt.attempts, got, tt.base)
+			}
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/hooks", true},
+		{"https://93.184.216.34:8443/hooks", true},
+		{"http://example.com/hooks", false},
+		{"ftp://example.com/hooks", false},
+		{"https:///hooks", false},
+		{"https://127.0.0.1/hooks", false},
+		{"https://[::1]/hooks", false},
+		{"https://10.0.0.8/hooks", false},
+		{"https://192.168.1.1/hooks", false},
+		{"https://169.254.169.254/computeMetadata/v1/", false},
+		{"https://[fe80::1]/hooks", false},
+		{"https://0.0.0.0/hooks", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if err := CheckURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("CheckURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckDial(t *testing.T) {
+	tests := []struct {
+		address string
+		ok      bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:443", false},
+		{"169.254.169.254:80", false},
+		{"172.16.0.1:443", false},
+		{"[fd00::1]:443", false},
+		{"[::ffff:127.0.0.1]:443", false},
+	}
+	for _, tt := range tests {
+		if err := checkDial("tcp", tt.address, nil); (err == nil) != tt.ok {
+			t.Errorf("checkDial(%q) = %v, want ok %v", tt.address, err, tt.ok)
+		}
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	followed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/target" {
+			followed = true
+			return
+		}
+		http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, so skip the address check
+	client := newClient(&net.Dialer{})
+	resp, err := client.Post(server.URL+"/hook", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTemporaryRedirect || followed {
+		t.Errorf("status = %d, followed = %v; want the redirect returned, not followed", resp.StatusCode, followed)
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := newClient(&net.Dialer{Control: checkDial})
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("client connected to a loopback address")
+	}
+}
@@ -1,6 +1,6 @@
-import { useState, useEffect } from 'react';
+import { useState, useEffect, useRef } from 'react';
 import { motion, AnimatePresence } from 'framer-motion';
-import { getAttendeeCount, registerAttendee } from '../services/api';
+import { attendeeCountStreamUrl, getAttendeeCount, problemMessage, registerAttendee } from '../services/api';
 
 const DESIGNATIONS = [
   'Developer',
@@ -19,9 +19,22 @@ function RegistrationForm() {
     email: '',
     designation: ''
   });
+  // Retrying the same details reuses the key, so a registration whose
+  // response was lost is not stored twice
+  const idempotencyKey = useRef(crypto.randomUUID());
+  useEffect(() => {
+    idempotencyKey.current = crypto.randomUUID();
+  }, [formData]);
 
   useEffect(() => {
     fetchCount();
+
+    // Live updates as other attendees register
+    const source = new EventSource(attendeeCountStreamUrl());
+    source.addEventListener('stats', (event) => {
+      setCount(JSON.parse((event as MessageEvent).data).count);
+    });
+    return () => source.close();
   }, []);
 
   const fetchCount = async () => {
@@ -43,13 +56,13 @@ function RegistrationForm() {
 
     setLoading(true);
     try {
-      await registerAttendee(formData);
+      await registerAttendee(formData, idempotencyKey.current);
       setShowSuccess(true);
       setFormData({ name: '', email: '', designation: '' });
       fetchCount(); // Refresh count
       setTimeout(() => setShowSuccess(false), 3000);
     } catch (error: any) {
-      alert(error.response?.data?.error || 'Registration failed. Please try again.');
+      alert(problemMessage(error, 'Registration failed. Please try again.'));
     } finally {
       setLoading(false);
     }
@@ -1,6 +1,6 @@
 import { useEffect, useState } from 'react';
 import { motion } from 'framer-motion';
-import { getSessions } from '../services/api';
+import { getSessions, mediaUrl } from '../services/api';
 
 interface Speaker {
   id: string;
@@ -17,6 +17,9 @@ interface Session {
   speakers: Speaker[];
 }
 
+// The fields rendered below; the API returns only these
+const SESSION_FIELDS = 'id,title,description,time,speakers.id,speakers.name,speakers.bio,speakers.photoUrl';
+
 function SessionsSection() {
   const [sessions, setSessions] = useState<Session[]>([]);
   const [loading, setLoading] = useState(true);
@@ -24,7 +27,7 @@ function SessionsSection() {
   useEffect(() => {
     const fetchSessions = async () => {
       try {
-        const response = await getSessions();
+        const response = await getSessions(SESSION_FIELDS);
         setSessions(response.data);
       } catch (error) {
         console.error('Failed to fetch sessions:', error);
@@ -98,7 +101,7 @@ function SessionsSection() {
                         <div key={speaker.id} className="flex items-center gap-3">
                           {speaker.photoUrl ? (
                             <img
-                              src={speaker.photoUrl}
+                              src={mediaUrl(speaker.photoUrl)}
                               alt={speaker.name}
                               className="w-10 h-10 rounded-full object-cover"
                             />
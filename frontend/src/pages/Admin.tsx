@@ -6,15 +6,21 @@ import {
   deleteAttendee,
   getAllSpeakers as getSpeakers,
   createSpeaker,
-  updateSpeaker,
+  patchSpeaker,
   deleteSpeaker,
   getSessions,
   createSession,
-  updateSession,
+  patchSession,
   deleteSession,
   getDesignationBreakdown,
+  getTrash,
+  restoreFromTrash,
+  purgeFromTrash,
   setAdminPassword,
+  problemMessage,
+  mediaUrl,
 } from '../services/api';
+import type { TrashKind } from '../services/api';
 import { PieChart, Pie, Cell, ResponsiveContainer, Legend, Tooltip } from 'recharts';
 
 interface Attendee {
@@ -31,6 +37,7 @@ interface Speaker {
   bio: string;
   photoUrl?: string;
   sessions?: string[];
+  version: number;
 }
 
 interface Session {
@@ -40,9 +47,20 @@ interface Session {
   time: string;
   speakerIds: string[];
   capacity?: number;
+  version: number;
 }
 
-type Tab = 'attendees' | 'speakers' | 'sessions' | 'analytics';
+interface TrashItem {
+  id: string;
+  kind: TrashKind;
+  deletedAt: string;
+  purgeAt: string;
+  item: { name?: string; title?: string };
+}
+
+type Tab = 'attendees' | 'speakers' | 'sessions' | 'analytics' | 'trash';
+
+const TRASH_KINDS: TrashKind[] = ['attendees', 'speakers', 'sessions'];
 
 const COLORS = ['#0ea5e9', '#0284c7', '#0369a1', '#075985', '#0c4a6e', '#7dd3fc', '#38bdf8'];
 
@@ -75,6 +93,9 @@ function Admin() {
   // Analytics state
   const [breakdown, setBreakdown] = useState<{ designation: string; count: number }[]>([]);
 
+  // Trash state
+  const [trash, setTrash] = useState<TrashItem[]>([]);
+
   useEffect(() => {
     // Check if admin is logged in
     const password = sessionStorage.getItem('adminPassword');
@@ -102,6 +123,9 @@ function Admin() {
       } else if (activeTab === 'analytics') {
         const response = await getDesignationBreakdown();
         setBreakdown(response.data);
+      } else if (activeTab === 'trash') {
+        const responses = await Promise.all(TRASH_KINDS.map((kind) => getTrash(kind)));
+        setTrash(responses.flatMap((response) => response.data));
       }
     } catch (error) {
       console.error('Failed to load data:', error);
@@ -115,8 +139,27 @@ function Admin() {
     loadData();
   }, [activeTab]);
 
+  const handleRestore = async (item: TrashItem) => {
+    try {
+      await restoreFromTrash(item.kind, item.id);
+      setTrash(trash.filter((t) => !(t.kind === item.kind && t.id === item.id)));
+    } catch (error: any) {
+      alert(problemMessage(error, 'Failed to restore'));
+    }
+  };
+
+  const handlePurge = async (item: TrashItem) => {
+    if (!confirm('Delete permanently? This cannot be undone.')) return;
+    try {
+      await purgeFromTrash(item.kind, item.id);
+      setTrash(trash.filter((t) => !(t.kind === item.kind && t.id === item.id)));
+    } catch (error) {
+      alert('Failed to delete');
+    }
+  };
+
   const handleDeleteAttendee = async (id: string) => {
-    if (!confirm('Are you sure you want to delete this attendee?')) return;
+    if (!confirm('Move this attendee to the trash? It can be restored from the Trash tab.')) return;
     try {
       await deleteAttendee(id);
       setAttendees(attendees.filter((a) => a.id !== id));
@@ -129,7 +172,7 @@ function Admin() {
     e.preventDefault();
     try {
       if (editingSpeaker) {
-        await updateSpeaker(editingSpeaker.id, speakerForm);
+        await patchSpeaker(editingSpeaker.id, speakerForm, editingSpeaker.version);
       } else {
         await createSpeaker(speakerForm);
       }
@@ -137,8 +180,12 @@ function Admin() {
       setEditingSpeaker(null);
       setSpeakerForm({ name: '', bio: '', photoUrl: '' });
       loadData();
-    } catch (error) {
-      alert('Failed to save speaker');
+    } catch (error: any) {
+      alert(
+        error.response?.status === 412
+          ? 'This speaker was changed by someone else. Reload and try again.'
+          : 'Failed to save speaker'
+      );
     }
   };
 
@@ -153,7 +200,7 @@ function Admin() {
   };
 
   const handleDeleteSpeaker = async (id: string) => {
-    if (!confirm('Are you sure you want to delete this speaker?')) return;
+    if (!confirm('Move this speaker to the trash? It can be restored from the Trash tab.')) return;
     try {
       await deleteSpeaker(id);
       setSpeakers(speakers.filter((s) => s.id !== id));
@@ -170,11 +217,11 @@ function Admin() {
         description: sessionForm.description,
         time: sessionForm.time,
         speakerIds: sessionForm.speakerIds,
-        capacity: sessionForm.capacity ? parseInt(sessionForm.capacity) : undefined,
+        capacity: sessionForm.capacity ? parseInt(sessionForm.capacity) : null,
       };
 
       if (editingSession) {
-        await updateSession(editingSession.id, sessionData);
+        await patchSession(editingSession.id, sessionData, editingSession.version);
       } else {
         await createSession(sessionData);
       }
@@ -182,8 +229,12 @@ function Admin() {
       setEditingSession(null);
       setSessionForm({ title: '', description: '', time: '', speakerIds: [], capacity: '' });
       loadData();
-    } catch (error) {
-      alert('Failed to save session');
+    } catch (error: any) {
+      alert(
+        error.response?.status === 412
+          ? 'This session was changed by someone else. Reload and try again.'
+          : 'Failed to save session'
+      );
     }
   };
 
@@ -200,7 +251,7 @@ function Admin() {
   };
 
   const handleDeleteSession = async (id: string) => {
-    if (!confirm('Are you sure you want to delete this session?')) return;
+    if (!confirm('Move this session to the trash? It can be restored from the Trash tab.')) return;
     try {
       await deleteSession(id);
       setSessions(sessions.filter((s) => s.id !== id));
@@ -245,7 +296,7 @@ function Admin() {
       <div className="container mx-auto px-4 py-8">
         {/* Tabs */}
         <div className="flex gap-2 mb-8 border-b border-gray-200">
-          {(['attendees', 'speakers', 'sessions', 'analytics'] as Tab[]).map((tab) => (
+          {(['attendees', 'speakers', 'sessions', 'analytics', 'trash'] as Tab[]).map((tab) => (
             <button
               key={tab}
               onClick={() => setActiveTab(tab)}
@@ -337,7 +388,7 @@ function Admin() {
                     <div key={speaker.id} className="border border-gray-200 rounded-lg p-4">
                       {speaker.photoUrl && (
                         <img
-                          src={speaker.photoUrl}
+                          src={mediaUrl(speaker.photoUrl)}
                           alt={speaker.name}
                           className="w-20 h-20 rounded-full object-cover mb-3"
                         />
@@ -424,6 +475,56 @@ function Admin() {
               </motion.div>
             )}
 
+            {/* Trash Tab */}
+            {activeTab === 'trash' && (
+              <motion.div
+                initial={{ opacity: 0, y: 20 }}
+                animate={{ opacity: 1, y: 0 }}
+                className="card"
+              >
+                <div className="flex justify-between items-center mb-6">
+                  <h2 className="text-2xl font-bold">Trash ({trash.length})</h2>
+                </div>
+                <div className="overflow-x-auto">
+                  <table className="w-full">
+                    <thead className="bg-gray-50">
+                      <tr>
+                        <th className="px-4 py-3 text-left text-sm font-semibold text-gray-700">Type</th>
+                        <th className="px-4 py-3 text-left text-sm font-semibold text-gray-700">Name</th>
+                        <th className="px-4 py-3 text-left text-sm font-semibold text-gray-700">Deleted</th>
+                        <th className="px-4 py-3 text-left text-sm font-semibold text-gray-700">Purged on</th>
+                        <th className="px-4 py-3 text-left text-sm font-semibold text-gray-700">Actions</th>
+                      </tr>
+                    </thead>
+                    <tbody className="divide-y divide-gray-200">
+                      {trash.map((item) => (
+                        <tr key={`${item.kind}-${item.id}`} className="hover:bg-gray-50">
+                          <td className="px-4 py-3 capitalize">{item.kind}</td>
+                          <td className="px-4 py-3">{item.item.name || item.item.title}</td>
+                          <td className="px-4 py-3">{new Date(item.deletedAt).toLocaleString()}</td>
+                          <td className="px-4 py-3">{new Date(item.purgeAt).toLocaleDateString()}</td>
+                          <td className="px-4 py-3 space-x-4">
+                            <button
+                              onClick={() => handleRestore(item)}
+                              className="text-primary-600 hover:text-primary-800 font-semibold"
+                            >
+                              Restore
+                            </button>
+                            <button
+                              onClick={() => handlePurge(item)}
+                              className="text-red-600 hover:text-red-800 font-semibold"
+                            >
+                              Delete forever
+                            </button>
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                </div>
+              </motion.div>
+            )}
+
             {/* Analytics Tab */}
             {activeTab === 'analytics' && (
               <motion.div
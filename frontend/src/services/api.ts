@@ -18,28 +18,160 @@ export const clearAdminPassword = () => {
   delete api.defaults.headers.common['X-Admin-Password'];
 };
 
+// Errors are RFC 7807 problem details (application/problem+json). code is
+// stable; detail and errors[].message are for people.
+export interface Problem {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  code: string;
+  errors?: { field: string; rule: string; message: string }[];
+}
+
+// problemMessage describes a failed request for an alert, falling back to
+// fallback when the response is not a problem
+export const problemMessage = (error: any, fallback: string) => {
+  const problem: Problem | undefined = error?.response?.data;
+  if (!problem?.code) return fallback;
+  if (problem.errors?.length) {
+    return problem.errors.map((e) => `${e.field} ${e.message}`).join('\n');
+  }
+  return problem.detail || problem.title || fallback;
+};
+
+// mediaUrl resolves a media link, which is relative unless the backend has
+// MEDIA_BASE_URL set, against the API's origin
+export const mediaUrl = (url: string) => new URL(url, new URL(API_URL, window.location.href)).href;
+
 // Public API
-export const getSessions = () => api.get('/sessions');
+export const getSessions = (fields?: string) => api.get('/sessions', { params: fields ? { fields } : {} });
 export const getSpeakers = () => api.get('/speakers');
 export const getAttendeeCount = () => api.get('/attendees/count');
-export const registerAttendee = (data: { name: string; email: string; designation: string }) =>
-  api.post('/attendees', data);
+export const attendeeCountStreamUrl = () => `${API_URL}/attendees/count/stream`;
+export const registerAttendee = (data: { name: string; email: string; designation: string }, idempotencyKey?: string) =>
+  api.post('/attendees', data, { headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : {} });
+export const getQuestions = (sessionId: string) => api.get(`/sessions/${sessionId}/questions`);
+export const askQuestion = (sessionId: string, data: { email: string; name?: string; text: string }) =>
+  api.post(`/sessions/${sessionId}/questions`, data);
+export const upvoteQuestion = (sessionId: string, questionId: string, email: string) =>
+  api.post(`/sessions/${sessionId}/questions/${questionId}/upvote`, { email });
+export const questionStreamUrl = (sessionId: string) => `${API_URL}/sessions/${sessionId}/questions/stream`;
+export const getPolls = (sessionId: string) => api.get(`/sessions/${sessionId}/polls`);
+export const votePoll = (sessionId: string, pollId: string, data: { email: string; optionIds: string[] }) =>
+  api.post(`/sessions/${sessionId}/polls/${pollId}/votes`, data);
+export const pollStreamUrl = (sessionId: string) => `${API_URL}/sessions/${sessionId}/polls/stream`;
+export const submitFeedback = (sessionId: string, data: { email: string; rating: number; comment?: string }) =>
+  api.post(`/sessions/${sessionId}/feedback`, data);
 
 // Admin API
-export const getAllAttendees = () => api.get('/admin/attendees');
+
+// Query parameters of the paginated admin list endpoints. Filters depend on
+// the endpoint, e.g. designation, from, to, session or speaker.
+export interface ListParams {
+  limit?: number;
+  cursor?: string;
+  sort?: string;
+  q?: string;
+  [filter: string]: string | number | undefined;
+}
+
+// Follows nextCursor through every page of an admin list endpoint
+const listAll = async (path: string, params: ListParams = {}) => {
+  const items: any[] = [];
+  let cursor: string | undefined;
+  do {
+    const response = await api.get(path, { params: { ...params, limit: 200, cursor } });
+    items.push(...response.data.items);
+    cursor = response.data.nextCursor || undefined;
+  } while (cursor);
+  return { data: items };
+};
+
+export const listAttendees = (params: ListParams = {}) => api.get('/admin/attendees', { params });
+export const listSpeakers = (params: ListParams = {}) => api.get('/admin/speakers', { params });
+export const listSessions = (params: ListParams = {}) => api.get('/admin/sessions', { params });
+export const getAllAttendees = () => listAll('/admin/attendees');
 export const getAttendee = (id: string) => api.get(`/admin/attendees/${id}`);
 export const deleteAttendee = (id: string) => api.delete(`/admin/attendees/${id}`);
 
-export const getAllSpeakers = () => api.get('/admin/speakers');
-export const createSpeaker = (data: any) => api.post('/admin/speakers', data);
-export const updateSpeaker = (id: string, data: any) => api.put(`/admin/speakers/${id}`, data);
+// Headers for versioned writes. The server requires the version read
+// earlier and rejects the write with 412 if someone else changed the resource.
+const ifMatch = (version: number) => ({ 'If-Match': `"${version}"` });
+const mergePatch = (version: number) => ({
+  headers: { 'Content-Type': 'application/merge-patch+json', ...ifMatch(version) },
+});
+
+// Speaker and session request bodies, as described by the Speaker and
+// Session schemas of the OpenAPI document (served at /api/openapi.json)
+export interface SpeakerInput {
+  name: string;
+  bio: string;
+  photoUrl?: string;
+}
+
+export interface SessionInput {
+  title: string;
+  description: string;
+  time: string;
+  speakerIds: string[];
+  capacity?: number | null;
+  startsAt?: string | null;
+  endsAt?: string | null;
+}
+
+// A JSON Merge Patch: omitted fields are kept, null clears a field
+export type MergePatch<T> = { [K in keyof T]?: T[K] | null };
+
+export const getAllSpeakers = () => listAll('/admin/speakers');
+export const createSpeaker = (data: SpeakerInput) => api.post('/admin/speakers', data);
+export const updateSpeaker = (id: string, data: SpeakerInput, version: number) =>
+  api.put(`/admin/speakers/${id}`, data, { headers: ifMatch(version) });
+export const patchSpeaker = (id: string, data: MergePatch<SpeakerInput>, version: number) =>
+  api.patch(`/admin/speakers/${id}`, data, mergePatch(version));
 export const deleteSpeaker = (id: string) => api.delete(`/admin/speakers/${id}`);
+export const uploadSpeakerPhoto = (id: string, photo: File) => {
+  const form = new FormData();
+  form.append('photo', photo);
+  return api.post(`/admin/speakers/${id}/photo`, form, {
+    headers: { 'Content-Type': 'multipart/form-data' },
+  });
+};
+export const deleteSpeakerPhoto = (id: string) => api.delete(`/admin/speakers/${id}/photo`);
 
-export const createSession = (data: any) => api.post('/admin/sessions', data);
-export const updateSession = (id: string, data: any) => api.put(`/admin/sessions/${id}`, data);
+export const createSession = (data: SessionInput) => api.post('/admin/sessions', data);
+export const updateSession = (id: string, data: SessionInput, version: number) =>
+  api.put(`/admin/sessions/${id}`, data, { headers: ifMatch(version) });
+export const patchSession = (id: string, data: MergePatch<SessionInput>, version: number) =>
+  api.patch(`/admin/sessions/${id}`, data, mergePatch(version));
 export const deleteSession = (id: string) => api.delete(`/admin/sessions/${id}`);
 
 export const getDesignationBreakdown = () => api.get('/admin/analytics/designation');
 
+export type TrashKind = 'attendees' | 'speakers' | 'sessions';
+export const getTrash = (kind: TrashKind) => api.get(`/admin/trash/${kind}`);
+export const restoreFromTrash = (kind: TrashKind, id: string) => api.post(`/admin/trash/${kind}/${id}/restore`);
+export const purgeFromTrash = (kind: TrashKind, id: string) => api.delete(`/admin/trash/${kind}/${id}`);
+
+export const getAllQuestions = (sessionId: string) => api.get(`/admin/sessions/${sessionId}/questions`);
+export const moderateQuestion = (
+  sessionId: string,
+  questionId: string,
+  data: { hidden?: boolean; answered?: boolean; pinned?: boolean }
+) => api.patch(`/admin/sessions/${sessionId}/questions/${questionId}`, data);
+
+export const getAllPolls = (sessionId: string) => api.get(`/admin/sessions/${sessionId}/polls`);
+export const createPoll = (sessionId: string, data: { question: string; options: string[]; multiple: boolean }) =>
+  api.post(`/admin/sessions/${sessionId}/polls`, data);
+export const openPoll = (sessionId: string, pollId: string) =>
+  api.post(`/admin/sessions/${sessionId}/polls/${pollId}/open`);
+export const closePoll = (sessionId: string, pollId: string) =>
+  api.post(`/admin/sessions/${sessionId}/polls/${pollId}/close`);
+export const getPollResults = () => api.get('/admin/analytics/polls');
+
+export const getSessionFeedback = () => api.get('/admin/feedback/sessions');
+export const getSpeakerFeedback = () => api.get('/admin/feedback/speakers');
+
 export default api;
 